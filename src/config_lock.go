@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Commands updating the config file, such as add-token and switch, may run at the same time, for example in parallel CI jobs.
+// They read, modify, and save the config holding an advisory lock of a lock file next to it,
+// so that one does not overwrite a change another has just made.
+// The lock file is kept rather than the config file itself locked, since the config file is replaced on every save.
+// Commands only reading the config do not take the lock, as the config file is never seen half written,
+// except when they find a config file of an older format or location, which they migrate holding the lock.
+
+// lockConfig takes an exclusive lock of the config file, waiting for other processes to release it.
+// The returned function releases the lock.
+func lockConfig() (func(), error) {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(configPath); err == nil {
+		configPath = resolved
+	}
+	return lockPath(configPath)
+}
+
+// lockPath takes an exclusive lock of path with a lock file named path.lock, creating its directory if needed.
+// The returned function releases the lock.
+func lockPath(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		if err := unlockFile(f); err != nil {
+			logger.Printf("[lockPath] unlocking %s failed, %s", path, err)
+		}
+		f.Close()
+	}, nil
+}
+
+// updateConfig loads the config, modifies it with update, and saves it, holding the lock of the config file throughout.
+// The config is not saved when update fails.
+func updateConfig(update func(conf *config) error) error {
+	unlock, err := lockConfig()
+	if err != nil {
+		logger.Printf("[updateConfig] locking config failed, %s", err)
+		return err
+	}
+	defer unlock()
+
+	conf := &config{}
+	if err := loadConfigLocked(conf); err != nil {
+		logger.Printf("[updateConfig] loading config failed, %s", err)
+		return err
+	}
+	if err := update(conf); err != nil {
+		return err
+	}
+	if err := saveConfig(conf); err != nil {
+		logger.Printf("[updateConfig] saving config failed, %s", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const helperAliasEnv = "SLACK_CLI_TEST_HELPER_ALIAS"
+
+func TestUpdateConfigConcurrently(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	saveTestWorkspaces(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			errs <- updateConfig(func(conf *config) error {
+				_, err := setAlias(conf, "workspace A", fmt.Sprintf("a%d", i))
+				return err
+			})
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			errs <- updateConfig(func(conf *config) error {
+				conf.CurrentWorkspace = []string{"000a", "000b"}[i%2]
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	conf := &config{}
+	if err := loadConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if conf.Aliases[fmt.Sprintf("a%d", i)] != "000a" {
+			t.Errorf("alias a%d is lost, got %v", i, conf.Aliases)
+		}
+	}
+	if len(conf.Workspaces) != 2 {
+		t.Errorf("expected 2 workspaces, got %v", conf.Workspaces)
+	}
+
+	configFilePath, err := getConfigFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(configFilePath + ".tmp*"); len(files) > 0 {
+		t.Errorf("temporary files are left, %v", files)
+	}
+}
+
+func TestUpdateConfigFromProcesses(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	saveTestWorkspaces(t)
+
+	configFilePath, err := getConfigFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each process adds an alias, as aliasWorkspace run by another command does
+	cmds := make([]*exec.Cmd, 10)
+	for i := range cmds {
+		cmds[i] = exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+		cmds[i].Env = append(os.Environ(), "SLACK_CLI_CONFIG="+configFilePath, fmt.Sprintf("%s=b%d", helperAliasEnv, i))
+		if err := cmds[i].Start(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("process %d failed, %s", i, err)
+		}
+	}
+
+	conf := &config{}
+	if err := loadConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	for i := range cmds {
+		if conf.Aliases[fmt.Sprintf("b%d", i)] != "000b" {
+			t.Errorf("alias b%d is lost, got %v", i, conf.Aliases)
+		}
+	}
+}
+
+// TestHelperProcess is run as another process by TestUpdateConfigFromProcesses.
+func TestHelperProcess(t *testing.T) {
+	alias := os.Getenv(helperAliasEnv)
+	if alias == "" {
+		return
+	}
+	if err := aliasWorkspace("workspace B", alias); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestMigrateConfigConcurrently(t *testing.T) {
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	defer func(name string) { legacyConfigFile = name }(legacyConfigFile)
+
+	configHome, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configHome)
+	os.Setenv("XDG_CONFIG_HOME", configHome)
+
+	homeDir, _ := homedir.Dir()
+	f, err := ioutil.TempFile(homeDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(legacyConfigString)
+	f.Close()
+	defer os.Remove(f.Name() + ".bak")
+	defer os.Remove(f.Name())
+	legacyConfigFile = filepath.Base(f.Name())
+
+	// every command reading the legacy file at once tries to move it
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if name, _, err := getCurrentWorkspace(); err != nil || name != "workspace A" {
+				t.Errorf("expected workspace A, got %q, %v", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if data, _ := ioutil.ReadFile(filepath.Join(configHome, "slack-cli", "config.toml")); string(data) != configString {
+		t.Errorf("config file should be moved, got\n%s", data)
+	}
+	if data, _ := ioutil.ReadFile(f.Name() + ".bak"); string(data) != legacyConfigString {
+		t.Errorf("legacy config file should be kept, got\n%s", data)
+	}
+}
@@ -1,100 +1,552 @@
-// Save workspace information and workspace context in toml file.
+// Save workspace information and workspace context in toml file,
+// which is $XDG_CONFIG_HOME/slack-cli/config.toml unless given with --config or SLACK_CLI_CONFIG.
 // Data format:
+// 		version = 2
+//		current_workspace = "T0001"
+//
 // 		[[workspaces]]
+// 		id = "T0001"
 // 		name = "workspace A"
-// 		token = "xoxo-hoge-a"
 // 		domain = "domain a"
 //
+// 		  [[workspaces.tokens]]
+// 		  kind = "bot"
+// 		  token = "xoxb-hoge-a"
+// 		  scopes = ["channels:read", "chat:write"]
+//
+// 		  [[workspaces.tokens]]
+// 		  kind = "user"
+// 		  token = "xoxp-hoge-a"
+//
 // 		[[workspaces]]
+// 		id = "T0002"
 // 		name = "workspace B"
-// 		token = "xoxo-hoge-b"
 // 		domain = "domain b"
 //
-//		current_workspace_token = "xoxo-hoge-a"
+// 		  [[workspaces.tokens]]
+// 		  kind = "bot"
+// 		  token = "xoxb-hoge-b"
 //
-// Switch workspace by modifying current_workspace_token
+//		[aliases]
+//		a = "T0001"
+//
+// Switch workspace by modifying current_workspace, or select one for an invocation with --workspace
+// The bot token of a workspace is used in preference to the user token unless --token-type is given.
+//
+// A config file of an older version, including ~/.slack_cmd_cli.toml used before, is migrated when loaded
+// and the old one is kept with .bak suffix.
+//
+// Tokens are encrypted when SLACK_CLI_PASSPHRASE is set, see token_crypt.go.
+// Tokens may be kept out of the file with token_env or token_command, see credential_store.go.
+// A token given with --token-file or SLACK_TOKEN is used without the file, in the order of precedence.
 package main
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/matthewlujp/slack-cmd-client/src/slack"
 	homedir "github.com/mitchellh/go-homedir"
 )
 
+// configVersion is the version of the config file format.
+// Version 0 wrote current_workspace_token as CurrentWorkspaceToken.
+// Version 1 held a token in each workspace and the current one by its token, see legacyConfig.
+const configVersion = 2
+
+// kinds of tokens
+const (
+	tokenBot  = "bot"
+	tokenUser = "user"
+)
+
 var (
-	defaultTokenFile = ".slack_cmd_cli.toml"
+	legacyConfigFile = ".slack_cmd_cli.toml" // under the home directory, used until version 1
+
+	errWorkspaceNotRegistered = errors.New("workspace is not registered")
+	errUnknownWorkspace       = errors.New("workspace of a token from --token-file or SLACK_TOKEN is unknown")
+	errConfigTooOpen          = errors.New("config file is too open")
+	errNoCurrentWorkspace     = errors.New("no workspace is current")
+	errExternalToken          = errors.New("token is kept out of the config file")
+	errWorkspaceWithToken     = errors.New("a workspace cannot be chosen along with a token from --token-file or SLACK_TOKEN")
 )
 
 type config struct {
-	Workspaces            []slack.Workspace `toml:"workspaces"`
-	CurrentWorkspaceToken string            `toml:"current_workspace_token`
+	Version          int               `toml:"version"`
+	CurrentWorkspace string            `toml:"current_workspace"` // ID of the current workspace
+	Workspaces       []workspaceConfig `toml:"workspaces"`
+	Aliases          map[string]string `toml:"aliases,omitempty"`         // alias to workspace ID
+	EncryptionSalt   string            `toml:"encryption_salt,omitempty"` // base64 salt of encrypted tokens
+
+	// current_workspace_token of version 1 or older, which tells the current workspace on migration
+	legacyCurrentToken string
+}
+
+// workspaceConfig is a registered workspace with its tokens
+type workspaceConfig struct {
+	ID     string        `toml:"id"`
+	Name   string        `toml:"name"`
+	Domain string        `toml:"domain"`
+	Tokens []tokenConfig `toml:"tokens"`
+}
+
+// tokenConfig is a token of a workspace, which is saved in the file unless kept elsewhere with TokenEnv or TokenCommand
+type tokenConfig struct {
+	Kind         string   `toml:"kind"` // bot or user, or empty if unknown until the token kept out of the file is read
+	Token        string   `toml:"token,omitempty"`
+	TokenEnv     string   `toml:"token_env,omitempty"`
+	TokenCommand string   `toml:"token_command,omitempty"`
+	Scopes       []string `toml:"scopes,omitempty"`
+}
+
+// tokenKind tells whether a token is a bot token or a user token from its prefix.
+func tokenKind(token string) string {
+	if strings.HasPrefix(token, "xoxb-") {
+		return tokenBot
+	}
+	return tokenUser
 }
 
+// token returns the token of kind, or the bot token in preference to the user token when kind is empty.
+// A token kept out of the config file is read from its store only here,
+// so that a store of another workspace or another kind, which may be unavailable, is never touched.
+func (w *workspaceConfig) token(kind string) (string, error) {
+	t, err := w.selectToken(kind)
+	if err != nil {
+		return "", err
+	}
+	token, err := newCredentialStore(*t).token(*w)
+	if err != nil {
+		return "", err
+	}
+	if t.Kind == "" && kind != "" && tokenKind(token) != kind {
+		return "", fmt.Errorf("workspace %s has no %s token, register one with add-token", w.Name, kind)
+	}
+	return token, nil
+}
+
+// selectToken returns the entry of the token of kind, or the bot one in preference to the user one when kind is empty.
+// A token of unknown kind is returned if no other matches, whose kind is checked once it is read.
+func (w *workspaceConfig) selectToken(kind string) (*tokenConfig, error) {
+	switch kind {
+	case "":
+		for _, k := range []string{tokenBot, tokenUser, ""} {
+			if t := w.findToken(k); t != nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("workspace %s has no token, register one with add-token", w.Name)
+	case tokenBot, tokenUser:
+		for _, k := range []string{kind, ""} {
+			if t := w.findToken(k); t != nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("workspace %s has no %s token, register one with add-token", w.Name, kind)
+	}
+	return nil, fmt.Errorf("unknown token type %q, which should be bot or user", kind)
+}
+
+func (w *workspaceConfig) findToken(kind string) *tokenConfig {
+	for i := range w.Tokens {
+		if w.Tokens[i].Kind == kind {
+			return &w.Tokens[i]
+		}
+	}
+	return nil
+}
+
+// setToken adds a token to the workspace, replacing the one of the same kind.
+func (w *workspaceConfig) setToken(t tokenConfig) error {
+	if err := w.checkReplaceable(t.Kind); err != nil {
+		return err
+	}
+	if existing := w.findToken(t.Kind); existing != nil {
+		*existing = t
+		return nil
+	}
+	w.Tokens = append(w.Tokens, t)
+	return nil
+}
+
+// checkReplaceable fails if the token of kind is kept out of the config file,
+// since a new token saved in the file would be dropped in favor of the one in the store.
+func (w *workspaceConfig) checkReplaceable(kind string) error {
+	t := w.findToken(kind)
+	if t == nil || newCredentialStore(*t).inFile() {
+		return nil
+	}
+	return fmt.Errorf("%w: %s token of workspace %s is kept in token_env or token_command, update it there or remove the entry from the config file",
+		errExternalToken, kind, w.Name)
+}
+
+// kinds returns kinds of tokens of the workspace.
+func (w *workspaceConfig) kinds() []string {
+	kinds := make([]string, 0, len(w.Tokens))
+	for _, t := range w.Tokens {
+		if t.Kind == "" {
+			kinds = append(kinds, "unknown")
+			continue
+		}
+		kinds = append(kinds, t.Kind)
+	}
+	return kinds
+}
+
+// clone returns a copy of conf which can be modified without affecting conf.
+func (conf *config) clone() *config {
+	c := *conf
+	c.Workspaces = make([]workspaceConfig, len(conf.Workspaces))
+	for i, w := range conf.Workspaces {
+		w.Tokens = append([]tokenConfig(nil), w.Tokens...)
+		c.Workspaces[i] = w
+	}
+	return &c
+}
+
+// legacyConfig is the format of version 1 or older.
+//
+//		current_workspace_token = "xoxo-hoge-a"
+//
+//		[[workspaces]]
+//		ID = "T0001"
+//		Name = "workspace A"
+//		Domain = "domain a"
+//		Token = "xoxo-hoge-a"
+//
+//		[credentials.T0002]
+//		token_command = "pass show slack/b"
+type legacyConfig struct {
+	Workspaces              []slack.Workspace `toml:"workspaces"`
+	CurrentWorkspaceToken   string            `toml:"current_workspace_token"`
+	CurrentWorkspaceTokenV0 string            `toml:"CurrentWorkspaceToken"`
+	Aliases                 map[string]string `toml:"aliases"`
+	EncryptionSalt          string            `toml:"encryption_salt"`
+	Credentials             map[string]struct {
+		TokenEnv     string `toml:"token_env"`
+		TokenCommand string `toml:"token_command"`
+	} `toml:"credentials"`
+}
+
+// convert turns the legacy format into the current one.
+// Kinds of tokens and the current workspace are left to be resolved after tokens are decrypted.
+func (l *legacyConfig) convert() config {
+	conf := config{
+		Aliases:            l.Aliases,
+		EncryptionSalt:     l.EncryptionSalt,
+		legacyCurrentToken: l.CurrentWorkspaceToken,
+	}
+	if conf.legacyCurrentToken == "" {
+		conf.legacyCurrentToken = l.CurrentWorkspaceTokenV0
+	}
+	for _, w := range l.Workspaces {
+		t := tokenConfig{Token: w.Token}
+		if c, ok := l.Credentials[w.ID]; ok {
+			t = tokenConfig{TokenEnv: c.TokenEnv, TokenCommand: c.TokenCommand}
+		}
+		conf.Workspaces = append(conf.Workspaces, workspaceConfig{
+			ID:     w.ID,
+			Name:   w.Name,
+			Domain: w.Domain,
+			Tokens: []tokenConfig{t},
+		})
+	}
+	return conf
+}
+
+// resolveLegacyFields fills kinds of tokens and the current workspace of a config converted from the legacy format.
+// The current workspace was given by its token, or a hash of it if the token was kept out of the file.
+// Tokens kept out of the file are not read here, so that their kinds are left unknown until they are used,
+// and the current workspace given by a hash is told only if a single workspace keeps its token out of the file.
+func resolveLegacyFields(conf *config) {
+	var external []string // IDs of workspaces keeping tokens out of the file
+	for i := range conf.Workspaces {
+		w := &conf.Workspaces[i]
+		for j := range w.Tokens {
+			t := &w.Tokens[j]
+			if t.Kind != "" {
+				continue
+			}
+			if !newCredentialStore(*t).inFile() {
+				external = append(external, w.ID)
+				continue
+			}
+			t.Kind = tokenKind(t.Token)
+			if conf.legacyCurrentToken != "" && (t.Token == conf.legacyCurrentToken || tokenHash(t.Token) == conf.legacyCurrentToken) {
+				conf.CurrentWorkspace = w.ID
+			}
+		}
+	}
+	if conf.legacyCurrentToken != "" && conf.CurrentWorkspace == "" {
+		if len(external) == 1 {
+			conf.CurrentWorkspace = external[0]
+		} else {
+			fmt.Fprintln(os.Stderr, "Warning: current workspace is unknown, choose one with switch")
+		}
+	}
+	conf.legacyCurrentToken = ""
+}
+
+// getConfigFilePath returns the path given with --config or SLACK_CLI_CONFIG,
+// or config.toml under slack-cli directory in $XDG_CONFIG_HOME, which defaults to ~/.config.
 func getConfigFilePath() (string, error) {
+	if *configFile != "" {
+		return *configFile, nil
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if !filepath.IsAbs(configHome) {
+		// relative paths are invalid in XDG Base Directory Specification
+		homeDir, err := homedir.Dir()
+		if err != nil {
+			logger.Printf("error when locating home dir, %s", err)
+			return "", err
+		}
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configHome, "slack-cli", "config.toml"), nil
+}
+
+// getLegacyConfigFilePath returns the path of the config file used until version 1.
+func getLegacyConfigFilePath() (string, error) {
 	homeDir, err := homedir.Dir()
 	if err != nil {
 		logger.Printf("error when locating home dir, %s", err)
 		return "", err
 	}
-	return filepath.Join(homeDir, defaultTokenFile), nil
+	return filepath.Join(homeDir, legacyConfigFile), nil
 }
 
+// loadConfig loads the config file into v without the lock of the config file.
+// A config file of an older format or location is migrated, which writes files, holding the lock.
 func loadConfig(v *config) error {
+	migrate, err := readConfig(v, false)
+	if err != nil || !migrate {
+		return err
+	}
+
+	unlock, err := lockConfig()
+	if err != nil {
+		logger.Printf("[loadConfig] locking config failed, %s", err)
+		return err
+	}
+	defer unlock()
+	// read again since another process may have migrated the file while waiting for the lock
+	*v = config{}
+	return loadConfigLocked(v)
+}
+
+// loadConfigLocked is loadConfig called holding the lock of the config file, which migrates the file at once.
+func loadConfigLocked(v *config) error {
+	_, err := readConfig(v, true)
+	return err
+}
+
+// readConfig reads the config file, or the legacy one if the config file does not exist, into v.
+// It migrates the file to the current format and location if locked is true,
+// and otherwise only reports whether the file should be migrated, leaving v to the read holding the lock.
+func readConfig(v *config, locked bool) (bool, error) {
 	configPath, err := getConfigFilePath()
 	if err != nil {
 		logger.Printf("[loadConfig] failed in getting path, %s", err)
-		return err
+		return false, err
 	}
 
-	if _, err := os.Stat(configPath); err != nil {
+	sourcePath := configPath
+	info, err := os.Stat(configPath)
+	if err != nil && *configFile == "" {
+		if !locked {
+			// another process may be moving the legacy file, so only the read holding the lock falls back to it
+			return legacyConfigExists(configPath)
+		}
+		// fall back to the legacy file, which is moved to the new path
+		if sourcePath, err = getLegacyConfigFilePath(); err != nil {
+			logger.Printf("[loadConfig] failed in getting path, %s", err)
+			return false, err
+		}
+		info, err = os.Stat(sourcePath)
+	}
+	if err != nil {
 		// no config file exist yet
-		return nil
+		return false, nil
 	}
 
-	if _, err := toml.DecodeFile(configPath, v); err != nil {
+	if err := decodeConfig(sourcePath, v); err != nil {
 		logger.Printf("[loadConfig] failed in decoding, %s", err)
-		return err
+		return false, err
 	}
-	return nil
+	migrate := sourcePath != configPath || (v.Version < configVersion && info.Size() > 0)
+	if migrate && !locked {
+		// warnings are left to the read holding the lock, so that they are printed once
+		return true, nil
+	}
+
+	if err := checkPermission(sourcePath, info.Mode()); err != nil {
+		return false, err
+	}
+	if err := decryptConfig(v); err != nil {
+		logger.Printf("[loadConfig] failed in decrypting tokens, %s", err)
+		return false, err
+	}
+	resolveLegacyFields(v)
+
+	if migrate {
+		if err := migrateConfig(v, sourcePath, configPath); err != nil {
+			logger.Printf("[loadConfig] failed in migrating %s, %s", sourcePath, err)
+			return false, err
+		}
+	}
+	v.Version = configVersion
+	return migrate, nil
 }
 
-// saveConfig saves given config struct as a toml file under the home directory.
-// If config file does not exist, create a new one.
-func saveConfig(conf *config) error {
-	configPath, err := getConfigFilePath()
+// legacyConfigExists reports whether the legacy config file exists and so should be migrated
+// to configPath, which does not exist. It also reports true if configPath appears meanwhile,
+// since another process has just migrated the file.
+func legacyConfigExists(configPath string) (bool, error) {
+	legacyPath, err := getLegacyConfigFilePath()
 	if err != nil {
-		logger.Printf("[saveConfig], %v", err)
+		logger.Printf("[loadConfig] failed in getting path, %s", err)
+		return false, err
+	}
+	if _, err := os.Stat(legacyPath); err == nil {
+		return true, nil
+	}
+	// the new file is written before the legacy one is moved, so one of them exists if any
+	_, err = os.Stat(configPath)
+	return err == nil, nil
+}
+
+// decodeConfig decodes a config file of any version into the current format.
+func decodeConfig(path string, v *config) error {
+	if _, err := toml.DecodeFile(path, v); err != nil {
+		return err
+	}
+	if v.Version >= 2 {
+		return nil
+	}
+	legacy := &legacyConfig{}
+	if _, err := toml.DecodeFile(path, legacy); err != nil {
 		return err
 	}
+	*v = legacy.convert()
+	return nil
+}
 
-	var f *os.File
-	if _, err := os.Stat(configPath); err != nil {
-		f, err = os.Create(configPath)
+// migrateConfig saves conf loaded from sourcePath to configPath in the current format, keeping the old file with .bak suffix.
+// The legacy file is renamed to the backup after saving, while a file migrated in place is copied before being overwritten.
+// The backup holds tokens in plain text as well, so it is made accessible only by the owner whatever the mode of the old file was.
+func migrateConfig(conf *config, sourcePath, configPath string) error {
+	backupPath := sourcePath + ".bak"
+	if sourcePath == configPath {
+		data, err := ioutil.ReadFile(sourcePath)
 		if err != nil {
-			logger.Printf("[saveConfig], %v", err)
 			return err
 		}
-	} else {
-		f, err = os.OpenFile(configPath, os.O_WRONLY|os.O_TRUNC, 0755)
-		if err != nil {
-			logger.Printf("[saveConfig], %v", err)
+		if err := ioutil.WriteFile(backupPath, data, 0600); err != nil {
 			return err
 		}
 	}
-	defer f.Close()
+	if err := saveConfig(conf); err != nil {
+		return err
+	}
+	if sourcePath != configPath {
+		if err := os.Rename(sourcePath, backupPath); err != nil {
+			return err
+		}
+	}
+	// WriteFile and Rename keep the mode of an existing file
+	if err := os.Chmod(backupPath, 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Migrated config file to %s, the old one is kept in %s\n", configPath, backupPath)
+	return nil
+}
 
-	w := bufio.NewWriter(f)
-	if err := toml.NewEncoder(w).Encode(conf); err != nil {
+// checkPermission refuses a config file which other users can write, and warns if they can read it.
+// The file should be accessible only by the owner since it holds tokens.
+func checkPermission(configPath string, mode os.FileMode) error {
+	if runtime.GOOS == "windows" {
+		// permission bits do not mean much on windows
+		return nil
+	}
+	if mode.Perm()&0022 != 0 {
+		return fmt.Errorf("%w: %s is writable by other users, run chmod 600 %s", errConfigTooOpen, configPath, configPath)
+	}
+	if mode.Perm()&0044 != 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %s is readable by other users, run chmod 600 %s\n", configPath, configPath)
+	}
+	return nil
+}
+
+// saveConfig saves given config struct as a toml file in the current version.
+// If config file does not exist, create a new one along with its directory.
+// The file is replaced at once with a temporary file only the owner can access, so that it is never left half written.
+func saveConfig(conf *config) error {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		logger.Printf("[saveConfig], %v", err)
+		return err
+	}
+	// replace the file a symbolic link points to rather than the link
+	if resolved, err := filepath.EvalSymlinks(configPath); err == nil {
+		configPath = resolved
+	}
+
+	stripped := stripCredentials(conf)
+	stripped.Version = configVersion
+	stripped.legacyCurrentToken = ""
+	encrypted, err := encryptConfig(stripped)
+	if err != nil {
+		logger.Printf("[saveConfig], %v", err)
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		logger.Printf("[saveConfig], %v", err)
+		return err
+	}
+	if err := replaceTOMLFile(configPath, encrypted); err != nil {
 		logger.Printf("[saveConfig], %v", err)
 		return err
 	}
 	return nil
 }
 
+// replaceTOMLFile encodes v into a temporary file only the owner can access, and renames it to path.
+// path is replaced at once, so that it is never left half written.
+func replaceTOMLFile(path string, v interface{}) error {
+	// a temporary file is created with 0600
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // fails after renamed
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := toml.NewEncoder(w).Encode(v); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
 func listWorkspaces() ([]string, error) {
 	conf := &config{}
 	if err := loadConfig(conf); err != nil {
@@ -108,19 +560,164 @@ func listWorkspaces() ([]string, error) {
 	return workspaces, nil
 }
 
-// getCurrentWorkspace returns current workspace name, its token, and an error if any
+// findWorkspace returns a registered workspace whose alias, name, ID, or domain is key.
+func findWorkspace(conf *config, key string) (*workspaceConfig, error) {
+	if id, ok := conf.Aliases[key]; ok {
+		key = id
+	}
+	for i, w := range conf.Workspaces {
+		if w.Name == key || w.ID == key || w.Domain == key {
+			return &conf.Workspaces[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errWorkspaceNotRegistered, key)
+}
+
+// currentWorkspace returns the workspace given with --workspace or SLACK_WORKSPACE, or the current one.
+func currentWorkspace(conf *config) (*workspaceConfig, error) {
+	if *selectedWorkspace != "" {
+		return findWorkspace(conf, *selectedWorkspace)
+	}
+	for i, w := range conf.Workspaces {
+		if w.ID == conf.CurrentWorkspace {
+			return &conf.Workspaces[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w, register one with add-token or switch to one", errNoCurrentWorkspace)
+}
+
+// workspaceAliases returns aliases of a workspace in order.
+func workspaceAliases(conf *config, workspaceID string) []string {
+	aliases := []string{} // never nil, so that json output has an empty list rather than null
+	for alias, id := range conf.Aliases {
+		if id == workspaceID {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// registeredWorkspace returns a workspace whose ID is id, or nil if it is not registered.
+// Unlike findWorkspace, only IDs are compared.
+func registeredWorkspace(conf *config, id string) *workspaceConfig {
+	for i := range conf.Workspaces {
+		if conf.Workspaces[i].ID == id {
+			return &conf.Workspaces[i]
+		}
+	}
+	return nil
+}
+
+// setAlias gives alias to a workspace whose alias, name, ID, or domain is key.
+// An alias which is already used by another workspace, or which is a name, ID, or domain of another workspace is refused.
+func setAlias(conf *config, key, alias string) (*workspaceConfig, error) {
+	if alias == "" {
+		return nil, errors.New("empty alias")
+	}
+	w, err := findWorkspace(conf, key)
+	if err != nil {
+		return nil, err
+	}
+	if other, err := findWorkspace(conf, alias); err == nil && other.ID != w.ID {
+		return nil, fmt.Errorf("alias %s is already used for workspace %s", alias, other.Name)
+	}
+	if conf.Aliases == nil {
+		conf.Aliases = make(map[string]string)
+	}
+	conf.Aliases[alias] = w.ID
+	return w, nil
+}
+
+// removeWorkspace unregisters a workspace whose alias, name, ID, or domain is key along with its aliases.
+// If it is the current workspace, no workspace becomes current.
+func removeWorkspace(conf *config, key string) (workspaceConfig, error) {
+	w, err := findWorkspace(conf, key)
+	if err != nil {
+		return workspaceConfig{}, err
+	}
+	removed := *w
+	for alias, id := range conf.Aliases {
+		if id == removed.ID {
+			delete(conf.Aliases, alias)
+		}
+	}
+	workspaces := conf.Workspaces[:0]
+	for _, w := range conf.Workspaces {
+		if w.ID != removed.ID {
+			workspaces = append(workspaces, w)
+		}
+	}
+	conf.Workspaces = workspaces
+	if conf.CurrentWorkspace == removed.ID {
+		conf.CurrentWorkspace = ""
+	}
+	return removed, nil
+}
+
+// envToken returns a token read from --token-file or SLACK_TOKEN in this order, or an empty string if neither is given.
+// It fails when a workspace is also given with --workspace or SLACK_WORKSPACE,
+// since the token belongs to its own workspace and the message would go somewhere else than asked.
+func envToken() (string, error) {
+	token := os.Getenv("SLACK_TOKEN")
+	if *tokenFile != "" {
+		data, err := ioutil.ReadFile(*tokenFile)
+		if err != nil {
+			logger.Printf("[envToken] reading token file failed, %s", err)
+			return "", err
+		}
+		if token = strings.TrimSpace(string(data)); token == "" {
+			return "", fmt.Errorf("token file %s is empty", *tokenFile)
+		}
+	}
+	if token != "" && *selectedWorkspace != "" {
+		return "", fmt.Errorf("%w, given %s", errWorkspaceWithToken, *selectedWorkspace)
+	}
+	return token, nil
+}
+
+// getCurrentWorkspace returns current workspace name, its token, and an error if any.
+// A token from --token-file or SLACK_TOKEN is returned without a name and the config file is not read at all.
+// Otherwise the workspace given with --workspace or SLACK_WORKSPACE takes the place of the current one,
+// and its token of the type given with --token-type is returned.
 func getCurrentWorkspace() (string, string, error) {
+	if token, err := envToken(); err != nil || token != "" {
+		return "", token, err
+	}
+
 	conf := &config{}
 	if err := loadConfig(conf); err != nil {
 		logger.Printf("[getCurrentWorkspaceToken] loading config failed, %s", err)
 		return "", "", err
 	}
+	w, err := currentWorkspace(conf)
+	if err != nil {
+		return "", "", err
+	}
+	token, err := w.token(*tokenType)
+	if err != nil {
+		return "", "", err
+	}
+	return w.Name, token, nil
+}
 
-	// search workspace name
-	for _, w := range conf.Workspaces {
-		if w.Token == conf.CurrentWorkspaceToken {
-			return w.Name, conf.CurrentWorkspaceToken, nil
-		}
+// getCurrentWorkspaceID returns ID of the current workspace, or the one given with --workspace or SLACK_WORKSPACE
+// It fails with a token from --token-file or SLACK_TOKEN, of which workspace is not recorded anywhere.
+func getCurrentWorkspaceID() (string, error) {
+	if token, err := envToken(); err != nil {
+		return "", err
+	} else if token != "" {
+		return "", errUnknownWorkspace
+	}
+
+	conf := &config{}
+	if err := loadConfig(conf); err != nil {
+		logger.Printf("[getCurrentWorkspaceID] loading config failed, %s", err)
+		return "", err
+	}
+	w, err := currentWorkspace(conf)
+	if err != nil {
+		return "", err
 	}
-	return "", conf.CurrentWorkspaceToken, nil
+	return w.ID, nil
 }
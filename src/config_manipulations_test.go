@@ -1,18 +1,54 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 
-	"github.com/matthewlujp/slack-cmd-client/src/slack"
 	homedir "github.com/mitchellh/go-homedir"
 )
 
 const (
-	configString = `CurrentWorkspaceToken = "xoxo-hoge-a"
+	configString = `version = 2
+current_workspace = "000a"
+
+[[workspaces]]
+  id = "000a"
+  name = "workspace A"
+  domain = "foo-bar"
+
+  [[workspaces.tokens]]
+    kind = "user"
+    token = "xoxo-hoge-a"
+`
+	// config file of version 1
+	v1ConfigString = `version = 1
+current_workspace_token = "sha256:%s"
+
+[[workspaces]]
+  ID = "000a"
+  Name = "workspace A"
+  Domain = "foo-bar"
+  Token = "xoxb-hoge-a"
+
+[[workspaces]]
+  ID = "000b"
+  Name = "workspace B"
+  Domain = "hoge-foo"
+  Token = ""
+
+[credentials]
+  [credentials.000b]
+    token_env = "SLACK_TOKEN_TEST"
+`
+	// config file of version 0
+	legacyConfigString = `CurrentWorkspaceToken = "xoxo-hoge-a"
 
 [[workspaces]]
   ID = "000a"
@@ -31,10 +67,13 @@ func setup() func() {
 	}
 	defer f.Close()
 	configFilePath := f.Name()
-	defaultTokenFile = filepath.Base(f.Name()) // overwrite config file path
+	*configFile = configFilePath // overwrite config file path
 
 	return func() {
 		os.Remove(configFilePath)
+		os.Remove(configFilePath + ".bak")
+		os.Remove(configFilePath + ".lock")
+		*configFile = ""
 	}
 }
 
@@ -60,14 +99,10 @@ func TestLoadConfig(t *testing.T) {
 		t.Error(err)
 	}
 	expected := config{
-		CurrentWorkspaceToken: "xoxo-hoge-a",
-		Workspaces: []slack.Workspace{
-			slack.Workspace{
-				ID:     "000a",
-				Name:   "workspace A",
-				Domain: "foo-bar",
-				Token:  "xoxo-hoge-a",
-			},
+		Version:          configVersion,
+		CurrentWorkspace: "000a",
+		Workspaces: []workspaceConfig{
+			{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-a"}}},
 		},
 	}
 	if !reflect.DeepEqual(*v, expected) {
@@ -81,14 +116,10 @@ func TestSaveConfig(t *testing.T) {
 	defer teardown()
 
 	conf := &config{
-		CurrentWorkspaceToken: "xoxo-hoge-a",
-		Workspaces: []slack.Workspace{
-			slack.Workspace{
-				ID:     "000a",
-				Name:   "workspace A",
-				Domain: "foo-bar",
-				Token:  "xoxo-hoge-a",
-			},
+		Version:          configVersion,
+		CurrentWorkspace: "000a",
+		Workspaces: []workspaceConfig{
+			{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-a"}}},
 		},
 	}
 
@@ -170,3 +201,370 @@ func TestGetCurrentWorkspace(t *testing.T) {
 	}
 
 }
+
+func TestGetSelectedWorkspace(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	defer func(s string) { *selectedWorkspace = s }(*selectedWorkspace)
+
+	conf := &config{
+		CurrentWorkspace: "000a",
+		Workspaces: []workspaceConfig{
+			{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-a"}}},
+			{ID: "000b", Name: "workspace B", Domain: "hoge-foo", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-b"}}},
+		},
+	}
+	if err := saveConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"workspace B", "000b", "hoge-foo"} {
+		*selectedWorkspace = key
+		if name, token, err := getCurrentWorkspace(); err != nil {
+			t.Error(err)
+		} else if name != "workspace B" || token != "xoxo-hoge-b" {
+			t.Errorf("%s: expected workspace B and xoxo-hoge-b, got %s and %s", key, name, token)
+		}
+		if id, err := getCurrentWorkspaceID(); err != nil || id != "000b" {
+			t.Errorf("%s: expected 000b, got %s, %v", key, id, err)
+		}
+	}
+
+	*selectedWorkspace = "workspace C"
+	if _, _, err := getCurrentWorkspace(); !errors.Is(err, errWorkspaceNotRegistered) {
+		t.Errorf("expected errWorkspaceNotRegistered, got %v", err)
+	}
+
+	// current workspace is kept as it is
+	loaded := &config{}
+	if err := loadConfig(loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.CurrentWorkspace != "000a" {
+		t.Errorf("current workspace should not be changed, got %s", loaded.CurrentWorkspace)
+	}
+}
+
+func TestEnvToken(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	defer os.Unsetenv("SLACK_TOKEN")
+	defer func(s string) { *tokenFile = s }(*tokenFile)
+	defer func(s string) { *selectedWorkspace = s }(*selectedWorkspace)
+
+	configFilePath, err := getConfigFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(configFilePath, []byte(configString), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("SLACK_TOKEN", "xoxo-env")
+	if name, token, err := getCurrentWorkspace(); err != nil || name != "" || token != "xoxo-env" {
+		t.Errorf("expected token in SLACK_TOKEN without name, got %q, %q, %v", name, token, err)
+	}
+	if _, err := getCurrentWorkspaceID(); !errors.Is(err, errUnknownWorkspace) {
+		t.Errorf("expected errUnknownWorkspace, got %v", err)
+	}
+
+	// --token-file takes precedence over SLACK_TOKEN
+	f, err := ioutil.TempFile("", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("xoxo-file\n")
+	f.Close()
+	*tokenFile = f.Name()
+	if _, token, err := getCurrentWorkspace(); err != nil || token != "xoxo-file" {
+		t.Errorf("expected token in the file, got %q, %v", token, err)
+	}
+
+	// the token does not tell which workspace it belongs to
+	*selectedWorkspace = "workspace A"
+	if _, _, err := getCurrentWorkspace(); !errors.Is(err, errWorkspaceWithToken) {
+		t.Errorf("expected errWorkspaceWithToken, got %v", err)
+	}
+	*selectedWorkspace = ""
+
+	*tokenFile = f.Name() + "-missing"
+	if _, _, err := getCurrentWorkspace(); err == nil {
+		t.Error("no error raised on missing token file")
+	}
+
+	// config file is used when neither is given
+	*tokenFile = ""
+	os.Unsetenv("SLACK_TOKEN")
+	if _, token, err := getCurrentWorkspace(); err != nil || token != "xoxo-hoge-a" {
+		t.Errorf("expected token in config, got %q, %v", token, err)
+	}
+}
+
+func TestConfigPermission(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	saveTestWorkspaces(t)
+
+	configFilePath, err := getConfigFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(configFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not checked on windows")
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("config file should be saved with 0600, got %o", info.Mode().Perm())
+	}
+
+	os.Chmod(configFilePath, 0666)
+	if err := loadConfig(&config{}); !errors.Is(err, errConfigTooOpen) {
+		t.Errorf("expected errConfigTooOpen on a file writable by others, got %v", err)
+	}
+	os.Chmod(configFilePath, 0644)
+	if err := loadConfig(&config{}); err != nil {
+		t.Errorf("a file readable by others should be loaded with a warning, got %v", err)
+	}
+
+	// saving fixes the permission
+	if err := saveConfig(&config{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(configFilePath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("config file should be saved with 0600, got %v, %v", info.Mode(), err)
+	}
+	if files, _ := filepath.Glob(configFilePath + ".tmp*"); len(files) > 0 {
+		t.Errorf("temporary files are left, %v", files)
+	}
+}
+
+func TestGetConfigFilePath(t *testing.T) {
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	homeDir, _ := homedir.Dir()
+
+	cases := []struct {
+		flag, configHome, expected string
+	}{
+		{"", "/tmp/config", "/tmp/config/slack-cli/config.toml"},
+		{"", "", filepath.Join(homeDir, ".config", "slack-cli", "config.toml")},
+		{"", "relative", filepath.Join(homeDir, ".config", "slack-cli", "config.toml")},
+		{"/tmp/slack.toml", "/tmp/config", "/tmp/slack.toml"},
+	}
+	for _, c := range cases {
+		*configFile = c.flag
+		os.Setenv("XDG_CONFIG_HOME", c.configHome)
+		if path, err := getConfigFilePath(); err != nil || path != filepath.FromSlash(c.expected) {
+			t.Errorf("%+v: expected %s, got %s, %v", c, c.expected, path, err)
+		}
+	}
+	*configFile = ""
+}
+
+func TestMigrateConfig(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	configFilePath, err := getConfigFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(configFilePath, []byte(legacyConfigString), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := new(config)
+	if err := loadConfig(v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Version != configVersion || v.CurrentWorkspace != "000a" {
+		t.Errorf("expected config of version %d with current workspace 000a, got %+v", configVersion, v)
+	}
+	if data, _ := ioutil.ReadFile(configFilePath); string(data) != configString {
+		t.Errorf("config file should be migrated to\n%s\n\nbut actually got\n\n%s", configString, data)
+	}
+	if data, _ := ioutil.ReadFile(configFilePath + ".bak"); string(data) != legacyConfigString {
+		t.Errorf("old config file should be kept, got\n%s", data)
+	}
+}
+
+func TestMigrateLegacyFile(t *testing.T) {
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	defer func(name string) { legacyConfigFile = name }(legacyConfigFile)
+
+	configHome, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configHome)
+	os.Setenv("XDG_CONFIG_HOME", configHome)
+
+	homeDir, _ := homedir.Dir()
+	f, err := ioutil.TempFile(homeDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(legacyConfigString)
+	f.Close()
+	os.Chmod(f.Name(), 0644) // mode of files created by older versions
+	defer os.Remove(f.Name() + ".bak")
+	defer os.Remove(f.Name())
+	legacyConfigFile = filepath.Base(f.Name())
+
+	stderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+	name, token, err := getCurrentWorkspace()
+	os.Stderr = stderr
+	w.Close()
+	output, _ := ioutil.ReadAll(r)
+	if err != nil || name != "workspace A" || token != "xoxo-hoge-a" {
+		t.Errorf("expected workspace A and xoxo-hoge-a, got %q, %q, %v", name, token, err)
+	}
+	if n := strings.Count(string(output), "readable by other users"); runtime.GOOS != "windows" && n != 1 {
+		t.Errorf("expected the permission warning once, got\n%s", output)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(configHome, "slack-cli", "config.toml")); string(data) != configString {
+		t.Errorf("config file should be moved, got\n%s", data)
+	}
+	if _, err := os.Stat(f.Name()); !os.IsNotExist(err) {
+		t.Errorf("legacy config file should be renamed, got %v", err)
+	}
+	if data, _ := ioutil.ReadFile(f.Name() + ".bak"); string(data) != legacyConfigString {
+		t.Errorf("legacy config file should be kept, got\n%s", data)
+	}
+	if info, err := os.Stat(f.Name() + ".bak"); err != nil {
+		t.Error(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("backup holding tokens should be accessible only by the owner, got %o", info.Mode().Perm())
+	}
+}
+
+func TestMigrateV1Config(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	// tokens kept out of the file are not read until used
+	os.Unsetenv("SLACK_TOKEN_TEST")
+	defer os.Unsetenv("SLACK_TOKEN_TEST")
+
+	configFilePath, err := getConfigFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the current workspace is B, whose token is in the environment variable
+	v1 := fmt.Sprintf(v1ConfigString, strings.TrimPrefix(tokenHash("xoxp-hoge-b"), "sha256:"))
+	if err := ioutil.WriteFile(configFilePath, []byte(v1), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := new(config)
+	if err := loadConfig(v); err != nil {
+		t.Fatal(err)
+	}
+	expected := config{
+		Version:          configVersion,
+		CurrentWorkspace: "000b",
+		Workspaces: []workspaceConfig{
+			{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []tokenConfig{{Kind: tokenBot, Token: "xoxb-hoge-a"}}},
+			{ID: "000b", Name: "workspace B", Domain: "hoge-foo", Tokens: []tokenConfig{{TokenEnv: "SLACK_TOKEN_TEST"}}},
+		},
+	}
+	if !reflect.DeepEqual(*v, expected) {
+		t.Errorf("config expected %+v, got %+v", expected, *v)
+	}
+
+	// kind of the token is told once it is read
+	os.Setenv("SLACK_TOKEN_TEST", "xoxp-hoge-b")
+	if token, err := v.Workspaces[1].token(""); err != nil || token != "xoxp-hoge-b" {
+		t.Errorf("expected the token in the environment variable, got %q, %v", token, err)
+	}
+	if token, err := v.Workspaces[1].token(tokenUser); err != nil || token != "xoxp-hoge-b" {
+		t.Errorf("expected the user token, got %q, %v", token, err)
+	}
+	if _, err := v.Workspaces[1].token(tokenBot); err == nil {
+		t.Error("no error raised on a token of another kind")
+	}
+
+	data, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sha256:") || strings.Contains(string(data), "xoxp-hoge-b") || !strings.Contains(string(data), `token_env = "SLACK_TOKEN_TEST"`) {
+		t.Errorf("config file should be migrated without the hash and the token kept out of it, got\n%s", data)
+	}
+	if data, _ := ioutil.ReadFile(configFilePath + ".bak"); string(data) != v1 {
+		t.Errorf("old config file should be kept, got\n%s", data)
+	}
+}
+
+func TestWorkspaceToken(t *testing.T) {
+	w := workspaceConfig{ID: "000a", Name: "workspace A", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxp-hoge-a"}}}
+	if token, err := w.token(""); err != nil || token != "xoxp-hoge-a" {
+		t.Errorf("expected the user token when it is the only one, got %q, %v", token, err)
+	}
+	if _, err := w.token(tokenBot); err == nil {
+		t.Error("no error raised on a missing bot token")
+	}
+
+	w.setToken(tokenConfig{Kind: tokenKind("xoxb-hoge-a"), Token: "xoxb-hoge-a"})
+	if token, err := w.token(""); err != nil || token != "xoxb-hoge-a" {
+		t.Errorf("expected the bot token to be preferred, got %q, %v", token, err)
+	}
+	if token, err := w.token(tokenUser); err != nil || token != "xoxp-hoge-a" {
+		t.Errorf("expected the user token, got %q, %v", token, err)
+	}
+	if _, err := w.token("admin"); err == nil {
+		t.Error("no error raised on an unknown kind of token")
+	}
+
+	// a token of the same kind is replaced
+	if err := w.setToken(tokenConfig{Kind: tokenBot, Token: "xoxb-hoge-a2"}); err != nil || len(w.Tokens) != 2 || w.Tokens[1].Token != "xoxb-hoge-a2" {
+		t.Errorf("bot token should be replaced, got %+v, %v", w.Tokens, err)
+	}
+
+	// a token kept out of the config file is read from its store, and is not replaced
+	os.Setenv("SLACK_TOKEN_TEST", "xoxp-env")
+	defer os.Unsetenv("SLACK_TOKEN_TEST")
+	w.Tokens[0] = tokenConfig{Kind: tokenUser, TokenEnv: "SLACK_TOKEN_TEST"}
+	if token, err := w.token(tokenUser); err != nil || token != "xoxp-env" {
+		t.Errorf("expected the token in the environment variable, got %q, %v", token, err)
+	}
+	if err := w.setToken(tokenConfig{Kind: tokenUser, Token: "xoxp-hoge-a2"}); !errors.Is(err, errExternalToken) {
+		t.Errorf("expected errExternalToken, got %v", err)
+	}
+	if kinds := w.kinds(); !reflect.DeepEqual(kinds, []string{tokenUser, tokenBot}) {
+		t.Errorf("expected user and bot, got %v", kinds)
+	}
+}
+
+func TestGetCurrentWorkspaceTokenType(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	defer func(s string) { *tokenType = s }(*tokenType)
+
+	conf := &config{
+		CurrentWorkspace: "000a",
+		Workspaces: []workspaceConfig{
+			{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []tokenConfig{
+				{Kind: tokenUser, Token: "xoxp-hoge-a"},
+				{Kind: tokenBot, Token: "xoxb-hoge-a"},
+			}},
+		},
+	}
+	if err := saveConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	for kind, expected := range map[string]string{"": "xoxb-hoge-a", tokenBot: "xoxb-hoge-a", tokenUser: "xoxp-hoge-a"} {
+		*tokenType = kind
+		if _, token, err := getCurrentWorkspace(); err != nil || token != expected {
+			t.Errorf("%q: expected %s, got %q, %v", kind, expected, token, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Tokens are saved in the config file by default.
+// A token may be kept elsewhere instead, such as an environment variable or a secret manager,
+// by token_env or token_command of the token in the config file.
+//
+//	[[workspaces.tokens]]
+//	kind = "bot"
+//	token_command = "pass show slack/acme"
+//
+//	[[workspaces.tokens]]
+//	kind = "user"
+//	token_env = "SLACK_USER_TOKEN_ACME"
+//
+// Then the config file holds only metadata of the token,
+// and the token is read from its store only when a command uses it.
+
+// credentialStore provides a token of a workspace
+type credentialStore interface {
+	// token returns a token of a workspace
+	token(w workspaceConfig) (string, error)
+	// inFile reports whether the token is saved in the config file
+	inFile() bool
+}
+
+// newCredentialStore returns a store of a token given by t.
+func newCredentialStore(t tokenConfig) credentialStore {
+	switch {
+	case t.TokenCommand != "":
+		return commandStore{command: t.TokenCommand}
+	case t.TokenEnv != "":
+		return envStore{name: t.TokenEnv}
+	}
+	return fileStore{saved: t.Token}
+}
+
+// fileStore keeps a token in the config file
+type fileStore struct {
+	saved string
+}
+
+func (s fileStore) token(w workspaceConfig) (string, error) {
+	return s.saved, nil
+}
+
+func (fileStore) inFile() bool {
+	return true
+}
+
+// envStore reads a token from an environment variable
+type envStore struct {
+	name string
+}
+
+func (s envStore) token(w workspaceConfig) (string, error) {
+	token := os.Getenv(s.name)
+	if token == "" {
+		return "", fmt.Errorf("token of workspace %s is not set to %s", w.Name, s.name)
+	}
+	return token, nil
+}
+
+func (envStore) inFile() bool {
+	return false
+}
+
+// commandStore reads a token from the first line of the output of a shell command.
+// The command can tell the workspace with SLACK_CLI_WORKSPACE_ID and SLACK_CLI_WORKSPACE_NAME environment variables.
+type commandStore struct {
+	command string
+}
+
+func (s commandStore) token(w workspaceConfig) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", s.command)
+	} else {
+		cmd = exec.Command("sh", "-c", s.command)
+	}
+	cmd.Env = append(os.Environ(), "SLACK_CLI_WORKSPACE_ID="+w.ID, "SLACK_CLI_WORKSPACE_NAME="+w.Name)
+	cmd.Stdin = nil // stdin may carry a message or a file to upload, which the command should not consume
+	cmd.Stderr = os.Stderr
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("token command of workspace %s failed, %s", w.Name, err)
+	}
+	token := strings.TrimSpace(strings.SplitN(out.String(), "\n", 2)[0])
+	if token == "" {
+		return "", fmt.Errorf("token command of workspace %s printed no token", w.Name)
+	}
+	return token, nil
+}
+
+func (commandStore) inFile() bool {
+	return false
+}
+
+// tokenHash returns a hash of a token, by which version 1 identified the current workspace without revealing the token.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// stripCredentials returns a copy of conf to be saved, from which tokens kept out of the config file are removed.
+// conf itself is left as it is so that callers keep using it.
+func stripCredentials(conf *config) *config {
+	stripped := conf.clone()
+	for _, w := range stripped.Workspaces {
+		for j, t := range w.Tokens {
+			if !newCredentialStore(t).inFile() {
+				w.Tokens[j].Token = ""
+			}
+		}
+	}
+	return stripped
+}
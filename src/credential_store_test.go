@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCredentialStore(t *testing.T) {
+	w := workspaceConfig{ID: "000a", Name: "workspace A"}
+
+	if token, err := newCredentialStore(tokenConfig{Token: "xoxo-file"}).token(w); err != nil || token != "xoxo-file" {
+		t.Errorf("expected token in the file, got %q, %v", token, err)
+	}
+
+	os.Setenv("SLACK_TOKEN_TEST", "xoxo-env")
+	defer os.Unsetenv("SLACK_TOKEN_TEST")
+	if token, err := newCredentialStore(tokenConfig{TokenEnv: "SLACK_TOKEN_TEST"}).token(w); err != nil || token != "xoxo-env" {
+		t.Errorf("expected token in the environment variable, got %q, %v", token, err)
+	}
+	if _, err := newCredentialStore(tokenConfig{TokenEnv: "SLACK_TOKEN_MISSING"}).token(w); err == nil {
+		t.Error("no error raised on an unset environment variable")
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("commands are written for sh")
+	}
+	if token, err := newCredentialStore(tokenConfig{TokenCommand: `printf 'xoxo-%s\nsecond line\n' "$SLACK_CLI_WORKSPACE_ID"`}).token(w); err != nil || token != "xoxo-000a" {
+		t.Errorf("expected the first line of the output, got %q, %v", token, err)
+	}
+	if _, err := newCredentialStore(tokenConfig{TokenCommand: "exit 1"}).token(w); err == nil {
+		t.Error("no error raised on a failed command")
+	}
+	if _, err := newCredentialStore(tokenConfig{TokenCommand: "true"}).token(w); err == nil {
+		t.Error("no error raised on a command printing nothing")
+	}
+
+	// stdin is left for a message or a file to upload
+	if token, err := newCredentialStore(tokenConfig{TokenCommand: "read line; echo xoxo-${line:-none}"}).token(w); err != nil || token != "xoxo-none" {
+		t.Errorf("expected the command not to read stdin, got %q, %v", token, err)
+	}
+}
+
+func TestSaveExternalCredentials(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	os.Setenv("SLACK_TOKEN_TEST", "xoxo-hoge-a")
+	defer os.Unsetenv("SLACK_TOKEN_TEST")
+
+	conf := &config{
+		CurrentWorkspace: "000a",
+		Workspaces: []workspaceConfig{
+			{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-a", TokenEnv: "SLACK_TOKEN_TEST"}}},
+			{ID: "000b", Name: "workspace B", Domain: "hoge-foo", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-b"}}},
+		},
+	}
+	if err := saveConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Workspaces[0].Tokens[0].Token != "xoxo-hoge-a" {
+		t.Errorf("config should not be modified, got %v", conf)
+	}
+
+	configFilePath, err := getConfigFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "xoxo-hoge-a") || !strings.Contains(string(data), "xoxo-hoge-b") {
+		t.Errorf("only the token kept in the file should be saved\n%s", data)
+	}
+
+	if name, token, err := getCurrentWorkspace(); err != nil || name != "workspace A" || token != "xoxo-hoge-a" {
+		t.Errorf("expected workspace A and the token in the environment variable, got %q, %q, %v", name, token, err)
+	}
+	if err := switchWorkspace("workspace B"); err != nil {
+		t.Fatal(err)
+	}
+	if name, token, err := getCurrentWorkspace(); err != nil || name != "workspace B" || token != "xoxo-hoge-b" {
+		t.Errorf("expected workspace B and xoxo-hoge-b, got %q, %q, %v", name, token, err)
+	}
+}
+
+func TestLazyCredentials(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	defer func(s string) { *selectedWorkspace = s }(*selectedWorkspace)
+	os.Unsetenv("SLACK_TOKEN_MISSING")
+
+	conf := &config{
+		CurrentWorkspace: "000a",
+		Workspaces: []workspaceConfig{
+			{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-a"}}},
+			{ID: "000b", Name: "workspace B", Domain: "hoge-foo", Tokens: []tokenConfig{{Kind: tokenUser, TokenEnv: "SLACK_TOKEN_MISSING"}}},
+		},
+	}
+	if err := saveConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	// an unavailable token of another workspace does not matter
+	if name, token, err := getCurrentWorkspace(); err != nil || name != "workspace A" || token != "xoxo-hoge-a" {
+		t.Errorf("expected workspace A and xoxo-hoge-a, got %q, %q, %v", name, token, err)
+	}
+	if err := switchWorkspace("workspace A"); err != nil {
+		t.Error(err)
+	}
+	if err := listRegisteredWorkspaces(ioutil.Discard); err != nil {
+		t.Error(err)
+	}
+
+	*selectedWorkspace = "workspace B"
+	if _, _, err := getCurrentWorkspace(); err == nil {
+		t.Error("no error raised on an unset environment variable of the workspace")
+	}
+}
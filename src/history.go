@@ -0,0 +1,153 @@
+// Record messages sent by this tool in a toml file, so that they can be edited or deleted later.
+// Data format:
+//
+//	[[messages]]
+//	workspace_id = "T0001"
+//	channel_id = "C0001"
+//	ts = "1500000000.000100"
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// maxHistoryPerWorkspace is the number of messages remembered in each workspace
+	maxHistoryPerWorkspace = 100
+)
+
+var (
+	errNoHistory = errors.New("no message sent by slack-cli is recorded")
+)
+
+type history struct {
+	Messages []sentMessage `toml:"messages"`
+}
+
+// sentMessage identifies a message posted by this tool
+type sentMessage struct {
+	WorkspaceID string `toml:"workspace_id"`
+	ChannelID   string `toml:"channel_id"`
+	TS          string `toml:"ts"`
+}
+
+// getHistoryFilePath returns the path of the history file, which is kept next to the config file
+// with its name followed by .history.toml, such as config.history.toml,
+// so that commands using different config files never act on messages of each other.
+func getHistoryFilePath() (string, error) {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(filepath.Base(configPath), filepath.Ext(configPath))
+	return filepath.Join(filepath.Dir(configPath), name+".history.toml"), nil
+}
+
+func loadHistory(h *history) error {
+	historyPath, err := getHistoryFilePath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(historyPath); err != nil {
+		// nothing recorded yet
+		return nil
+	}
+	if _, err := toml.DecodeFile(historyPath, h); err != nil {
+		logger.Printf("[loadHistory] failed in decoding, %s", err)
+		return err
+	}
+	return nil
+}
+
+// saveHistory replaces the history file at once, so that it is never left half written.
+func saveHistory(h *history) error {
+	historyPath, err := getHistoryFilePath()
+	if err != nil {
+		return err
+	}
+	if err := replaceTOMLFile(historyPath, h); err != nil {
+		logger.Printf("[saveHistory] %s", err)
+		return err
+	}
+	return nil
+}
+
+// updateHistory loads the history, modifies it with update, and saves it, holding the lock of the history file throughout,
+// so that messages sent at the same time by other processes are not lost.
+func updateHistory(update func(h *history)) error {
+	historyPath, err := getHistoryFilePath()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockPath(historyPath)
+	if err != nil {
+		logger.Printf("[updateHistory] locking history failed, %s", err)
+		return err
+	}
+	defer unlock()
+
+	h := &history{}
+	if err := loadHistory(h); err != nil {
+		return err
+	}
+	update(h)
+	return saveHistory(h)
+}
+
+// recordMessage appends a sent message to the history, dropping the oldest ones of the workspace over the limit.
+func recordMessage(workspaceID, channelID, ts string) error {
+	return updateHistory(func(h *history) {
+		h.Messages = append(h.Messages, sentMessage{WorkspaceID: workspaceID, ChannelID: channelID, TS: ts})
+
+		count := 0
+		kept := make([]sentMessage, 0, len(h.Messages))
+		for i := len(h.Messages) - 1; i >= 0; i-- {
+			if m := h.Messages[i]; m.WorkspaceID == workspaceID {
+				if count++; count > maxHistoryPerWorkspace {
+					continue
+				}
+			}
+			kept = append(kept, h.Messages[i])
+		}
+		// restore chronological order
+		for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+			kept[i], kept[j] = kept[j], kept[i]
+		}
+		h.Messages = kept
+	})
+}
+
+// lastMessage returns the latest message sent in a workspace.
+// If channelID is not empty, the latest one in the channel is returned.
+func lastMessage(workspaceID, channelID string) (*sentMessage, error) {
+	h := &history{}
+	if err := loadHistory(h); err != nil {
+		return nil, err
+	}
+	for i := len(h.Messages) - 1; i >= 0; i-- {
+		m := h.Messages[i]
+		if m.WorkspaceID == workspaceID && (channelID == "" || m.ChannelID == channelID) {
+			return &m, nil
+		}
+	}
+	return nil, errNoHistory
+}
+
+// forgetMessage removes a message from the history, typically after it is deleted.
+func forgetMessage(workspaceID, channelID, ts string) error {
+	return updateHistory(func(h *history) {
+		kept := h.Messages[:0]
+		for _, m := range h.Messages {
+			if m.WorkspaceID == workspaceID && m.ChannelID == channelID && m.TS == ts {
+				continue
+			}
+			kept = append(kept, m)
+		}
+		h.Messages = kept
+	})
+}
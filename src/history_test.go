@@ -0,0 +1,141 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func setupHistory() func() {
+	// the history is kept next to a tmp config file
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		panic(err)
+	}
+	*configFile = filepath.Join(dir, "config.toml")
+
+	return func() {
+		*configFile = ""
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLastMessage(t *testing.T) {
+	teardown := setupHistory()
+	defer teardown()
+
+	if _, err := lastMessage("T1", ""); err != errNoHistory {
+		t.Errorf("expected no history error, got %v", err)
+	}
+
+	for _, m := range []sentMessage{
+		{WorkspaceID: "T1", ChannelID: "C1", TS: "1.0"},
+		{WorkspaceID: "T1", ChannelID: "C2", TS: "2.0"},
+		{WorkspaceID: "T2", ChannelID: "C3", TS: "3.0"},
+	} {
+		if err := recordMessage(m.WorkspaceID, m.ChannelID, m.TS); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		workspaceID, channelID string
+		expected               sentMessage
+	}{
+		{"T1", "", sentMessage{WorkspaceID: "T1", ChannelID: "C2", TS: "2.0"}},
+		{"T1", "C1", sentMessage{WorkspaceID: "T1", ChannelID: "C1", TS: "1.0"}},
+		{"T2", "", sentMessage{WorkspaceID: "T2", ChannelID: "C3", TS: "3.0"}},
+	}
+	for _, c := range cases {
+		if m, err := lastMessage(c.workspaceID, c.channelID); err != nil {
+			t.Error(err)
+		} else if !reflect.DeepEqual(*m, c.expected) {
+			t.Errorf("expected %v, got %v", c.expected, *m)
+		}
+	}
+
+	// forget deleted message
+	if err := forgetMessage("T1", "C2", "2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := lastMessage("T1", ""); err != nil {
+		t.Error(err)
+	} else if m.TS != "1.0" {
+		t.Errorf("expected 1.0 after forgetting 2.0, got %s", m.TS)
+	}
+}
+
+func TestRecordMessageLimit(t *testing.T) {
+	teardown := setupHistory()
+	defer teardown()
+
+	recordMessage("T2", "C1", "0.0")
+	for i := 0; i < maxHistoryPerWorkspace+10; i++ {
+		if err := recordMessage("T1", "C1", strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &history{}
+	if err := loadHistory(h); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Messages) != maxHistoryPerWorkspace+1 {
+		t.Errorf("expected %d messages, got %d", maxHistoryPerWorkspace+1, len(h.Messages))
+	}
+	if h.Messages[0].WorkspaceID != "T2" {
+		t.Error("messages of another workspace should be kept")
+	}
+	if h.Messages[1].TS != "10" {
+		t.Errorf("oldest messages should be dropped, got %s at the beginning", h.Messages[1].TS)
+	}
+}
+
+func TestRecordMessageConcurrently(t *testing.T) {
+	teardown := setupHistory()
+	defer teardown()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := recordMessage("T1", "C1", strconv.Itoa(i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	h := &history{}
+	if err := loadHistory(h); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Messages) != 20 {
+		t.Errorf("expected 20 messages recorded, got %v", h.Messages)
+	}
+}
+
+func TestGetHistoryFilePath(t *testing.T) {
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", "/tmp/config")
+
+	cases := []struct {
+		flag, expected string
+	}{
+		{"", "/tmp/config/slack-cli/config.history.toml"},
+		{"/tmp/team.toml", "/tmp/team.history.toml"},
+		{"/tmp/slack", "/tmp/slack.history.toml"},
+	}
+	for _, c := range cases {
+		*configFile = c.flag
+		if path, err := getHistoryFilePath(); err != nil || path != filepath.FromSlash(c.expected) {
+			t.Errorf("%+v: expected %s, got %s, %v", c, c.expected, path, err)
+		}
+	}
+	*configFile = ""
+}
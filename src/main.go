@@ -2,34 +2,153 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+var (
+	logger           = log.New(os.Stderr, "", log.LstdFlags) // kept off stdout, which scripts parse
+	uploadCmd        = flag.NewFlagSet("uplaod", flag.ExitOnError)
+	uploadFileTitle  = uploadCmd.String("t", "", "designate a title for the uploaded file")
+	uploadComment    = uploadCmd.String("m", "", "add initial comments to the uploaded file")
+	uploadFileName   = uploadCmd.String("n", "", "designate a file name, which defaults to the base name of file_path or \"stdin\"")
+	uploadNoProgress = uploadCmd.Bool("no-progress", false, "do not report upload progress")
+	uploadThread     = uploadCmd.String("thread", "", "upload the file as a reply in the thread of a message with the timestamp")
+	uploadBroadcast  = uploadCmd.Bool("broadcast", false, "show the reply in the channel as well, used with --thread")
+	uploadSpool      = uploadCmd.Bool("spool", false, "copy stdin to a temporary file first, so that it can be uploaded where files.upload is not available")
+
+	messageCmd       = flag.NewFlagSet("message", flag.ExitOnError)
+	messageCode      = messageCmd.Bool("code", false, "wrap the message in a code block")
+	messageSplit     = messageCmd.Bool("split", false, "split a long message into several messages")
+	messageTruncate  = messageCmd.Bool("truncate", false, "truncate a long message")
+	messageLimit     = messageCmd.Int("limit", maxMessageLength, "maximum number of characters in a message")
+	messageThread    = messageCmd.String("thread", "", "reply in the thread of a message with the timestamp")
+	messageBroadcast = messageCmd.Bool("broadcast", false, "show the reply in the channel as well, used with --thread")
+	messagePrintTS   = messageCmd.Bool("print-ts", false, "print only timestamps of posted messages, which can be passed to --thread")
+	messageColor     = messageCmd.String("color", "", "post the message in an attachment with a side bar of good, warning, danger, or a hex code like #439FE0")
+	messageTitleLink = messageCmd.String("title-link", "", "link the first line of the message in an attachment to the url")
+	messageFields    fieldFlags
+	messageBlocks    = messageCmd.String("blocks", "", "lay out the message with Block Kit blocks in a json file, or stdin when \"-\"")
+
+	addTokenCmd = flag.NewFlagSet("add-token", flag.ExitOnError)
+	addTokenYes = addTokenCmd.Bool("yes", false, "register the workspace without confirmation")
+
+	editCmd    = flag.NewFlagSet("edit", flag.ExitOnError)
+	editLast   = editCmd.Bool("last", false, "edit the latest message sent by slack-cli")
+	deleteCmd  = flag.NewFlagSet("delete", flag.ExitOnError)
+	deleteLast = deleteCmd.Bool("last", false, "delete the latest message sent by slack-cli")
+
+	historyCmd   = flag.NewFlagSet("history", flag.ExitOnError)
+	historySince = historyCmd.String("since", "", "read messages posted within a duration such as 30m, 2h, or 3d")
+	historyLimit = historyCmd.Int("limit", 100, "maximum number of latest messages, 0 for all")
+
+	threadCmd = flag.NewFlagSet("thread", flag.ExitOnError)
+
+	tailCmd      = flag.NewFlagSet("tail", flag.ExitOnError)
+	tailAppToken = tailCmd.String("app-token", "", "app-level token with connections:write scope, which defaults to SLACK_APP_TOKEN") // not a flag default, which usage prints
 )
 
+// global flags, which can be placed anywhere in the command line
 var (
-	logger          = log.New(os.Stdout, "", log.LstdFlags)
-	uploadCmd       = flag.NewFlagSet("uplaod", flag.ExitOnError)
-	uploadFileTitle = uploadCmd.String("t", "", "designate a title for the uploaded file")
-	uploadComment   = uploadCmd.String("m", "", "add initial comments to the uploaded file")
+	globalCmd = flag.NewFlagSet("slack-cli", flag.ExitOnError)
+
+	selectedWorkspace = globalCmd.String("workspace", os.Getenv("SLACK_WORKSPACE"), "name, ID, domain, or alias of a registered workspace used instead of the current one, which defaults to SLACK_WORKSPACE")
+	configFile        = globalCmd.String("config", os.Getenv("SLACK_CLI_CONFIG"), "path of the config file, which defaults to SLACK_CLI_CONFIG or $XDG_CONFIG_HOME/slack-cli/config.toml")
+	tokenType         = globalCmd.String("token-type", "", "use the bot or user token of the workspace, which defaults to the bot token if registered")
+	tokenFile         = globalCmd.String("token-file", "", "read a token from the file instead of SLACK_TOKEN or the config file")
 )
 
+// splitGlobalFlags separates global flags from arguments of a subcommand.
+// Arguments after "--" are left as they are.
+func splitGlobalFlags(args []string) (global, rest []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return global, append(rest, args[i:]...)
+		}
+		name := strings.TrimLeft(arg, "-")
+		if !strings.HasPrefix(arg, "-") || name == "" {
+			rest = append(rest, arg)
+			continue
+		}
+		hasValue := false
+		if j := strings.Index(name, "="); j >= 0 {
+			name, hasValue = name[:j], true
+		}
+		f := globalCmd.Lookup(name)
+		if f == nil {
+			rest = append(rest, arg)
+			continue
+		}
+		global = append(global, arg)
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			global = append(global, args[i])
+		}
+	}
+	return global, rest
+}
+
+func init() {
+	messageCmd.Var(&messageFields, "field", "add a key=value field to the attachment, which can be repeated")
+}
+
 const (
-	cmdUsage = `  a) add-token token: create token file under the home directory
-  b) switch: switch context workspace (from registered token)
+	cmdUsage = `  a) add-token token [--yes]: add a token to the config file, creating it if missing
+  b) switch [name_id_or_alias]: switch context workspace (from registered token)
   c) list: list channels to which you can upload a file
-  d) message channel_id_or_name message_content: send message to a designated channel
-  e) upload channel_id_or_name file_path [-t title] [-m comment]: upload a file`
+  d) message channel_id_or_name [message_content|-] [--code] [--split|--truncate] [--limit n] [--thread ts [--broadcast]] [--print-ts] [--blocks file.json|-] [--color color] [--field key=value]... [--title-link url]: send message to a designated channel (read from stdin when "-" or omitted with piped input)
+  e) upload channel_id_or_name file_path [-t title] [-m comment] [-n name] [--no-progress] [--spool] [--thread ts [--broadcast]]: upload a file (file_path "-" reads stdin)
+  f) edit channel_id_or_name ts message_content | edit --last [channel_id_or_name] message_content: edit a message
+  g) delete channel_id_or_name ts | delete --last [channel_id_or_name]: delete a message
+  h) tail channel_id_or_name [--app-token token]: print messages posted to a channel as they arrive
+  i) history channel_id_or_name [--since 2h] [--limit n]: print recent messages of a channel
+  j) thread channel_id_or_name ts: print a message and its replies
+  k) workspaces list|current|remove workspace|alias workspace alias: manage registered workspaces, whose aliases can be used in place of names
+
+Global flags, which can be placed anywhere:
+  --output text|json|yaml|csv|table|template: output format of list, history (markdown as well), and thread
+  --template '{{.Name}}': Go text/template executed for each item with --output template
+  --workspace name_id_or_alias: use a registered workspace for this invocation without switching (SLACK_WORKSPACE as well)
+  --token-file path: read a token from the file, skipping the config file
+  --token-type bot|user: choose a token of the workspace, which defaults to the bot token if registered
+  --config path: use the config file instead of $XDG_CONFIG_HOME/slack-cli/config.toml (SLACK_CLI_CONFIG as well)
+
+A token is taken from --token-file, SLACK_TOKEN environment variable, and the config file in this order of precedence.
+Commands work with --token-file or SLACK_TOKEN without add-token, except for edit and delete with --last.
+When stdin is not a terminal, add-token without --yes and switch without a workspace fail instead of asking.`
 )
 
 // Call this script with one of following subcommands
-// add-token token: create token file under the home directory and add a given token to the file
-// switch: switch context workspace (from registered token)
+// add-token token: add a given token to the config file, which is created if missing
+// switch [name_or_id]: switch context workspace (from registered token)
 // list: list channels to which you can upload a file
 // message channel_id_or_name: upload a file
 // upload channel_id_or_name file_path -t title -m comment: upload a file
+// edit channel_id_or_name ts message_content: edit a message
+// delete channel_id_or_name ts: delete a message
+// tail channel_id_or_name: print new messages of a channel
+// history channel_id_or_name: print recent messages of a channel
+// thread channel_id_or_name ts: print a message and its replies
+// workspaces list|current|remove|alias: manage registered workspaces
 func main() {
+	global, args := splitGlobalFlags(os.Args[1:])
+	globalCmd.Parse(global)
+	os.Args = append(os.Args[:1], args...)
+
 	if len(os.Args) < 2 {
 		fmt.Printf("Please provide valid subcommands.\n%s\n", cmdUsage)
 		os.Exit(1)
@@ -39,49 +158,340 @@ func main() {
 		os.Exit(0)
 	}
 
+	// cancel in-flight requests on interrupt
+	ctx, cancel := withInterrupt(context.Background())
+	defer cancel()
+
 	switch os.Args[1] {
 	case "add-token":
-		fmt.Println("Create new token file under the home directory.")
-		if len(os.Args) < 3 || os.Args[2] == "" {
-			fmt.Println("Usage: add-token token\nPlease provide a valid token.")
+		if path, err := getConfigFilePath(); err == nil {
+			fmt.Printf("Add the token to the config file %s.\n", path)
+		}
+		// the flag may be placed either before or after the token
+		addTokenCmd.Parse(os.Args[2:])
+		args := addTokenCmd.Args()
+		if len(args) > 1 {
+			addTokenCmd.Parse(args[1:])
+		}
+		if len(args) < 1 || args[0] == "" {
+			fmt.Println("Usage: add-token token [--yes]\nPlease provide a valid token.")
 			os.Exit(1)
 		}
-		if err := registerToken(os.Args[2]); err != nil {
-			fmt.Println(err)
+		if err := registerToken(ctx, args[0], *addTokenYes); err != nil {
+			printError(err)
 			os.Exit(1)
 		}
 	case "switch":
 		fmt.Println("Switching workspace.")
-		if err := switchWorkspace(); err != nil {
-			logger.Fatalf("failed to switch workspace, %s", err)
+		var key string
+		if len(os.Args) > 2 {
+			key = os.Args[2]
+		}
+		if err := switchWorkspace(key); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
+	case "workspaces":
+		if err := runWorkspacesCommand(os.Args[2:]); err != nil {
+			printError(err)
+			os.Exit(1)
 		}
 	case "list":
-		if err := listChannels(); err != nil {
+		if err := checkOutput(*outputFormat, *outputTmpl); err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+		if err := listChannels(ctx); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
 	case "message":
-		if len(os.Args) < 4 {
-			fmt.Println("Usage: message channel_id_or_name message_content")
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: message channel_id_or_name [message_content|-] [--code] [--split|--truncate] [--limit n] [--thread ts [--broadcast]] [--print-ts] [--blocks file.json|-] [--color color] [--field key=value]... [--title-link url]")
 			os.Exit(1)
 		}
-		if err := sendMessage(os.Args[2], os.Args[3]); err != nil {
+		messages, err := parseMessageArgs(os.Args[3:])
+		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+		opts := postOptions{threadTS: *messageThread, broadcast: *messageBroadcast, printTS: *messagePrintTS}
+		if *messageBlocks != "" {
+			if opts.blocks, err = readBlocks(*messageBlocks); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		if hasAttachmentFlags() {
+			// the text is shown in the attachment instead of the message
+			attachment, err := buildAttachment(messages[0], *messageColor, *messageTitleLink, messageFields)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			opts.attachments = []slack.Attachment{attachment}
+			messages = []string{""}
+		}
+		if err := sendMessage(ctx, os.Args[2], messages, opts); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
 	case "upload":
 		if len(os.Args) < 4 {
-			fmt.Println("Usage: upload channel_id_or_name filepath [-t title] [-m comment]")
+			fmt.Println("Usage: upload channel_id_or_name filepath [-t title] [-m comment] [-n name] [--no-progress] [--spool] [--thread ts [--broadcast]]")
 			os.Exit(1)
 		}
 		uploadCmd.Parse(os.Args[4:])
-		if err := uploadFile(os.Args[2], os.Args[3], *uploadFileTitle, *uploadComment); err != nil {
+		if *uploadBroadcast && *uploadThread == "" {
+			fmt.Println("--broadcast should be used with --thread")
+			os.Exit(1)
+		}
+		if err := uploadFile(ctx, os.Args[2], os.Args[3], *uploadFileName, *uploadFileTitle, *uploadComment, *uploadThread, *uploadBroadcast, *uploadSpool, !*uploadNoProgress); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
+	case "edit":
+		editCmd.Parse(os.Args[2:])
+		args := editCmd.Args()
+		var channel, ts, text string
+		switch {
+		case *editLast && len(args) == 1:
+			text = args[0]
+		case *editLast && len(args) == 2:
+			channel, text = args[0], args[1]
+		case !*editLast && len(args) == 3:
+			channel, ts, text = args[0], args[1], args[2]
+		default:
+			fmt.Println("Usage: edit channel_id_or_name ts message_content\n       edit --last [channel_id_or_name] message_content")
+			os.Exit(1)
+		}
+		if err := editMessage(ctx, channel, ts, text, *editLast); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
+	case "delete":
+		deleteCmd.Parse(os.Args[2:])
+		args := deleteCmd.Args()
+		var channel, ts string
+		switch {
+		case *deleteLast && len(args) <= 1:
+			if len(args) == 1 {
+				channel = args[0]
+			}
+		case !*deleteLast && len(args) == 2:
+			channel, ts = args[0], args[1]
+		default:
+			fmt.Println("Usage: delete channel_id_or_name ts\n       delete --last [channel_id_or_name]")
+			os.Exit(1)
+		}
+		if err := deleteMessage(ctx, channel, ts, *deleteLast); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
+	case "tail":
+		if len(os.Args) < 3 || strings.HasPrefix(os.Args[2], "-") {
+			fmt.Println("Usage: tail channel_id_or_name [--app-token token]")
+			os.Exit(1)
+		}
+		tailCmd.Parse(os.Args[3:])
+		if err := tailChannel(ctx, os.Args[2], *tailAppToken); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
+	case "history":
+		if len(os.Args) < 3 || strings.HasPrefix(os.Args[2], "-") {
+			fmt.Println("Usage: history channel_id_or_name [--since 2h] [--limit n]")
+			os.Exit(1)
+		}
+		historyCmd.Parse(os.Args[3:])
+		var since time.Duration
+		if *historySince != "" {
+			var err error
+			if since, err = parseSince(*historySince); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		if err := checkOutput(*outputFormat, *outputTmpl, outputMarkdown); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := showHistory(ctx, os.Args[2], since, *historyLimit); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
+	case "thread":
+		if len(os.Args) < 4 || strings.HasPrefix(os.Args[2], "-") || strings.HasPrefix(os.Args[3], "-") {
+			fmt.Println("Usage: thread channel_id_or_name ts")
+			os.Exit(1)
+		}
+		threadCmd.Parse(os.Args[4:])
+		if err := checkOutput(*outputFormat, *outputTmpl, outputMarkdown); err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+		if err := showThread(ctx, os.Args[2], os.Args[3]); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
 	default:
 		fmt.Println(uploadFileTitle, uploadComment)
 		fmt.Printf("Subcommand %s is not supported.\n%s", os.Args[1], cmdUsage)
 		os.Exit(1)
 	}
 }
+
+// withInterrupt returns a context which is cancelled when SIGINT or SIGTERM is received.
+func withInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(sig)
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// parseMessageArgs reads message content and flags following the channel of message subcommand.
+// Content is read from stdin when it is "-", or when it is omitted and stdin is not a terminal.
+func parseMessageArgs(args []string) ([]string, error) {
+	var content string
+	if len(args) > 0 && (args[0] == "-" || !strings.HasPrefix(args[0], "-")) {
+		content, args = args[0], args[1:]
+	}
+	messageCmd.Parse(args)
+	// content may follow flags as well
+	if rest := messageCmd.Args(); len(rest) > 0 {
+		if content != "" || len(rest) > 1 {
+			return nil, fmt.Errorf("unexpected arguments %q, quote the message to send it as one", rest)
+		}
+		content = rest[0]
+	}
+	if *messageSplit && *messageTruncate {
+		return nil, errors.New("--split and --truncate cannot be used together")
+	}
+	if *messageBroadcast && *messageThread == "" {
+		return nil, errors.New("--broadcast should be used with --thread")
+	}
+	if *messageSplit && hasAttachmentFlags() {
+		return nil, errors.New("--split cannot be used with --color, --field, and --title-link")
+	}
+
+	if *messageBlocks != "" {
+		// content is optional with blocks, which is shown in notifications
+		if content == "-" {
+			if *messageBlocks == "-" {
+				return nil, errors.New("message content and --blocks cannot both be read from stdin")
+			}
+			var err error
+			if content, err = readMessage(os.Stdin); err != nil {
+				return nil, err
+			}
+		}
+		return []string{content}, nil
+	}
+
+	if content == "-" || (content == "" && !isTerminal(os.Stdin)) {
+		var err error
+		if content, err = readMessage(os.Stdin); err != nil {
+			return nil, err
+		}
+	}
+
+	overflow := overflowError
+	if *messageSplit {
+		overflow = overflowSplit
+	} else if *messageTruncate {
+		overflow = overflowTruncate
+	}
+	return buildMessages(content, *messageCode, *messageLimit, overflow)
+}
+
+// hasAttachmentFlags tells whether the message should be posted in an attachment.
+func hasAttachmentFlags() bool {
+	return *messageColor != "" || *messageTitleLink != "" || len(messageFields) > 0
+}
+
+const workspacesUsage = "Usage: workspaces list|current|remove workspace|alias workspace alias"
+
+// runWorkspacesCommand runs one of subcommands of workspaces.
+func runWorkspacesCommand(args []string) error {
+	if len(args) < 1 {
+		return errors.New(workspacesUsage)
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		if err := checkOutput(*outputFormat, *outputTmpl); err != nil {
+			return err
+		}
+		return listRegisteredWorkspaces(os.Stdout)
+	case args[0] == "current" && len(args) == 1:
+		if err := checkOutput(*outputFormat, *outputTmpl); err != nil {
+			return err
+		}
+		return printCurrentWorkspace(os.Stdout)
+	case args[0] == "remove" && len(args) == 2:
+		return unregisterWorkspace(args[1])
+	case args[0] == "alias" && len(args) == 3:
+		return aliasWorkspace(args[1], args[2])
+	}
+	return errors.New(workspacesUsage)
+}
+
+// printError prints err followed by a hint to resolve it, if any.
+func printError(err error) {
+	fmt.Println(err)
+	if hint := errorHint(err); hint != "" {
+		fmt.Println(hint)
+	}
+}
+
+// errorHint returns an actionable message for well-known Slack errors.
+func errorHint(err error) string {
+	if errors.Is(err, slack.ErrSocketModeDisabled) {
+		return "Enable Socket Mode of the app at https://api.slack.com/apps."
+	}
+	if errors.Is(err, errNotInteractive) {
+		return "Run it in a terminal, or pass --yes to add-token and a workspace name to switch in scripts."
+	}
+	if errors.Is(err, errUnknownWorkspace) {
+		return "Messages sent with --token-file or SLACK_TOKEN are not remembered. Give the channel and timestamp instead of --last."
+	}
+	if errors.Is(err, errExternalToken) {
+		return "Put the new token where token_env or token_command of the workspace points, or remove them from the config file and run add-token again."
+	}
+	if errors.Is(err, errWorkspaceWithToken) {
+		return "Drop --workspace and SLACK_WORKSPACE to use the token, or unset SLACK_TOKEN and drop --token-file to use the registered workspace."
+	}
+	if errors.Is(err, errNoCurrentWorkspace) {
+		return "Register a workspace with add-token, or choose one with switch."
+	}
+	if errors.Is(err, errWorkspaceNotRegistered) {
+		return "Give a name, ID, domain, or alias of a workspace registered with add-token. Check them with workspaces list."
+	}
+	var apiErr *slack.APIError
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	switch {
+	case errors.Is(err, slack.ErrInvalidAuth), errors.Is(err, slack.ErrNotAuthed), errors.Is(err, slack.ErrTokenRevoked), errors.Is(err, slack.ErrAccountInactive):
+		return "The token of the current workspace is not valid. Register a new one with add-token."
+	case errors.Is(err, slack.ErrMissingScope):
+		if apiErr.Needed != "" {
+			return fmt.Sprintf("The token lacks %s scope. Grant it at https://api.slack.com/apps and reinstall the app.", apiErr.Needed)
+		}
+		return "The token lacks a required scope. Grant it at https://api.slack.com/apps and reinstall the app."
+	case errors.Is(err, slack.ErrChannelNotFound):
+		return "The channel was not found. Check available channels with list."
+	case errors.Is(err, slack.ErrNotInChannel):
+		return "You are not a member of the channel. Join or invite the app to the channel first."
+	case errors.Is(err, slack.ErrRateLimited):
+		return "Slack is rate limiting requests. Try again later."
+	}
+	return ""
+}
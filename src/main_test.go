@@ -0,0 +1,25 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitGlobalFlags(t *testing.T) {
+	cases := []struct {
+		args           []string
+		global, others []string
+	}{
+		{[]string{"--output", "json", "list"}, []string{"--output", "json"}, []string{"list"}},
+		{[]string{"list", "-output=csv"}, []string{"-output=csv"}, []string{"list"}},
+		{[]string{"history", "c1", "--since", "2h", "--output", "table"}, []string{"--output", "table"}, []string{"history", "c1", "--since", "2h"}},
+		{[]string{"message", "c1", "hi", "--workspace", "b", "--code"}, []string{"--workspace", "b"}, []string{"message", "c1", "hi", "--code"}},
+		{[]string{"message", "c1", "-", "--", "--output"}, nil, []string{"message", "c1", "-", "--", "--output"}},
+	}
+	for _, c := range cases {
+		global, others := splitGlobalFlags(c.args)
+		if !reflect.DeepEqual(global, c.global) || !reflect.DeepEqual(others, c.others) {
+			t.Errorf("%v: expected %v and %v, got %v and %v", c.args, c.global, c.others, global, others)
+		}
+	}
+}
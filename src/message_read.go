@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// parseSince parses a duration such as "2h" or "30m", and days such as "3d" as well.
+func parseSince(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q, which should be like 30m, 2h, or 3d", s)
+	}
+	return d, nil
+}
+
+// renderedMessage is a message with the author name resolved
+type renderedMessage struct {
+	TS         string           `json:"ts" yaml:"ts"`
+	Time       time.Time        `json:"time" yaml:"time"`
+	User       string           `json:"user,omitempty" yaml:"user,omitempty"`
+	UserName   string           `json:"user_name" yaml:"user_name"`
+	Text       string           `json:"text" yaml:"text"`
+	ThreadTS   string           `json:"thread_ts,omitempty" yaml:"thread_ts,omitempty"`
+	ReplyCount int              `json:"reply_count,omitempty" yaml:"reply_count,omitempty"`
+	Files      []string         `json:"files,omitempty" yaml:"files,omitempty"`
+	Reactions  []slack.Reaction `json:"reactions,omitempty" yaml:"reactions,omitempty"`
+}
+
+func newRenderedMessage(m slack.Message, members slack.Members) renderedMessage {
+	r := renderedMessage{
+		TS:         m.TS,
+		Time:       m.Time(),
+		User:       m.User,
+		UserName:   authorName(m.User, m.Username, members),
+		Text:       m.Text,
+		ThreadTS:   m.ThreadTS,
+		ReplyCount: m.ReplyCount,
+		Reactions:  m.Reactions,
+	}
+	for _, f := range m.Files {
+		r.Files = append(r.Files, f.Name)
+	}
+	return r
+}
+
+// renderMessages writes messages in order in format, which is text, markdown, or one of common output formats.
+func renderMessages(w io.Writer, format, tmpl string, messages []slack.Message, members slack.Members) error {
+	rendered := make([]renderedMessage, 0, len(messages))
+	for _, m := range messages {
+		rendered = append(rendered, newRenderedMessage(m, members))
+	}
+
+	switch format {
+	case outputMarkdown:
+		for _, m := range rendered {
+			if _, err := io.WriteString(w, markdownMessage(m)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputText:
+		for _, m := range rendered {
+			if _, err := fmt.Fprintln(w, textMessage(m)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return renderOutput(w, format, tmpl, rendered)
+}
+
+// textMessage renders a message as a line such as "2017-07-14 11:40:00 taro: hello [file: a.png] (2 replies)".
+func textMessage(m renderedMessage) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s: %s", m.Time.Format(timeLayout), m.UserName, m.Text)
+	for _, f := range m.Files {
+		fmt.Fprintf(&b, " [file: %s]", f)
+	}
+	for _, r := range m.Reactions {
+		fmt.Fprintf(&b, " %s", r)
+	}
+	if m.ReplyCount > 0 {
+		fmt.Fprintf(&b, " (%d replies)", m.ReplyCount)
+	}
+	return b.String()
+}
+
+// markdownMessage renders a message as a paragraph headed by the author and time.
+func markdownMessage(m renderedMessage) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** %s\n\n", m.UserName, m.Time.Format(timeLayout))
+	if m.Text != "" {
+		fmt.Fprintf(&b, "%s\n\n", m.Text)
+	}
+	for _, f := range m.Files {
+		fmt.Fprintf(&b, "- file: %s\n", f)
+	}
+	if len(m.Reactions) > 0 {
+		reactions := make([]string, 0, len(m.Reactions))
+		for _, r := range m.Reactions {
+			reactions = append(reactions, r.String())
+		}
+		fmt.Fprintf(&b, "- reactions: %s\n", strings.Join(reactions, " "))
+	}
+	if m.ReplyCount > 0 {
+		fmt.Fprintf(&b, "- %d replies\n", m.ReplyCount)
+	}
+	if len(m.Files) > 0 || len(m.Reactions) > 0 || m.ReplyCount > 0 {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
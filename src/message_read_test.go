@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+func TestParseSince(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected time.Duration
+		valid    bool
+	}{
+		{"2h", 2 * time.Hour, true},
+		{"30m", 30 * time.Minute, true},
+		{"3d", 72 * time.Hour, true},
+		{"-1h", 0, false},
+		{"d", 0, false},
+		{"hoge", 0, false},
+	}
+	for _, c := range cases {
+		d, err := parseSince(c.input)
+		if c.valid && (err != nil || d != c.expected) {
+			t.Errorf("%s: expected %s, got %s (%v)", c.input, c.expected, d, err)
+		} else if !c.valid && err == nil {
+			t.Errorf("%s: no error raised", c.input)
+		}
+	}
+}
+
+func TestRenderMessages(t *testing.T) {
+	members := slack.Members{{ID: "U1", Name: "taro"}}
+	messages := []slack.Message{
+		{TS: "1500000000.000100", User: "U1", Text: "hoge", ReplyCount: 2},
+		{TS: "1500000100.000100", BotID: "B1", Username: "ci", Text: "build log", Files: []slack.File{{Name: "log.txt"}}, Reactions: []slack.Reaction{{Name: "+1", Count: 3}}},
+	}
+	t0 := time.Unix(1500000000, 0).Format(timeLayout)
+	t1 := time.Unix(1500000100, 0).Format(timeLayout)
+
+	var b bytes.Buffer
+	if err := renderMessages(&b, outputText, "", messages, members); err != nil {
+		t.Fatal(err)
+	}
+	expected := t0 + " taro: hoge (2 replies)\n" + t1 + " ci: build log [file: log.txt] :+1: 3\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+
+	b.Reset()
+	if err := renderMessages(&b, outputMarkdown, "", messages, members); err != nil {
+		t.Fatal(err)
+	}
+	expected = "**taro** " + t0 + "\n\nhoge\n\n- 2 replies\n\n" +
+		"**ci** " + t1 + "\n\nbuild log\n\n- file: log.txt\n- reactions: :+1: 3\n\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+
+	b.Reset()
+	if err := renderMessages(&b, outputJSON, "", messages, members); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []renderedMessage
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0].UserName != "taro" || decoded[1].Files[0] != "log.txt" || !strings.Contains(b.String(), `"ts": "1500000000.000100"`) {
+		t.Errorf("unexpected json %s", b.String())
+	}
+
+	if err := renderMessages(&b, "xml", "", messages, members); err == nil {
+		t.Error("no error raised on unknown format")
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+const (
+	// maxMessageLength is the number of characters Slack accepts in a message text.
+	// See https://api.slack.com/methods/chat.postMessage#truncating
+	maxMessageLength = 40000
+
+	codeFence       = "```"
+	truncatedMarker = "\n...(truncated)"
+)
+
+// overflow modes telling how to handle a message longer than the limit
+const (
+	overflowError    = "error"
+	overflowTruncate = "truncate"
+	overflowSplit    = "split"
+)
+
+// readMessage reads whole message text from r, removing trailing new lines.
+func readMessage(r io.Reader) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("message is not valid UTF-8 text")
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// readBlocks reads Block Kit blocks from a json file, or stdin when path is "-".
+// Blocks are validated, so that mistakes are reported before posting.
+func readBlocks(path string) (slack.Blocks, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := slack.ParseBlocks(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blocks in %s, %s", path, err)
+	}
+	return blocks, nil
+}
+
+// buildMessages turns text into messages to post, each of which fits in limit characters.
+// When code is true, each message is wrapped in a code block.
+// overflow is one of overflowError, overflowTruncate, and overflowSplit.
+func buildMessages(text string, code bool, limit int, overflow string) ([]string, error) {
+	if text == "" {
+		return nil, fmt.Errorf("message is empty")
+	}
+
+	// room for code fences
+	wrap := func(s string) string { return s }
+	if code {
+		limit -= 2*utf8.RuneCountInString(codeFence) + 2
+		wrap = func(s string) string { return codeFence + "\n" + s + "\n" + codeFence }
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("message size limit is too small")
+	}
+
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{wrap(text)}, nil
+	}
+
+	switch overflow {
+	case overflowTruncate:
+		markerLength := utf8.RuneCountInString(truncatedMarker)
+		if limit <= markerLength {
+			return []string{wrap(string([]rune(text)[:limit]))}, nil
+		}
+		return []string{wrap(string([]rune(text)[:limit-markerLength]) + truncatedMarker)}, nil
+	case overflowSplit:
+		chunks := splitText(text, limit)
+		messages := make([]string, 0, len(chunks))
+		for _, c := range chunks {
+			messages = append(messages, wrap(c))
+		}
+		return messages, nil
+	}
+	return nil, fmt.Errorf("message has %d characters, which exceeds the limit of %d, use --split or --truncate", utf8.RuneCountInString(text), limit)
+}
+
+// splitText splits text into chunks of at most limit characters, breaking at new lines when possible.
+func splitText(text string, limit int) []string {
+	var chunks []string
+	runes := []rune(text)
+	for len(runes) > limit {
+		cut := limit
+		// break after the last new line within the limit
+		for i := limit - 1; i > 0; i-- {
+			if runes[i] == '\n' {
+				cut = i + 1
+				break
+			}
+		}
+		if chunk := strings.TrimRight(string(runes[:cut]), "\n"); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
+
+// fieldFlags collects repeated --field key=value flags as attachment fields
+type fieldFlags []slack.AttachmentField
+
+func (f *fieldFlags) String() string {
+	pairs := make([]string, 0, len(*f))
+	for _, field := range *f {
+		pairs = append(pairs, field.Title+"="+field.Value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f *fieldFlags) Set(v string) error {
+	i := strings.Index(v, "=")
+	if i <= 0 {
+		return fmt.Errorf("field should be key=value, got %q", v)
+	}
+	*f = append(*f, slack.AttachmentField{Title: v[:i], Value: v[i+1:], Short: true})
+	return nil
+}
+
+// buildAttachment puts text in an attachment for status posts.
+// If titleLink is given, the first line of text becomes the title linked to it, and the rest becomes the body.
+func buildAttachment(text, color, titleLink string, fields []slack.AttachmentField) (slack.Attachment, error) {
+	a := slack.Attachment{
+		Fallback:   text,
+		Color:      color,
+		TitleLink:  titleLink,
+		Fields:     fields,
+		Footer:     "slack-cli",
+		TS:         time.Now().Unix(),
+		MarkdownIn: []string{"text"},
+	}
+	a.Text = text
+	if titleLink != "" {
+		lines := strings.SplitN(text, "\n", 2)
+		a.Title, a.Text = lines[0], ""
+		if len(lines) > 1 {
+			a.Text = lines[1]
+		}
+	}
+	if err := a.Validate(); err != nil {
+		return slack.Attachment{}, err
+	}
+	return a, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadMessage(t *testing.T) {
+	if msg, err := readMessage(strings.NewReader("line1\nline2\n\n")); err != nil {
+		t.Error(err)
+	} else if msg != "line1\nline2" {
+		t.Errorf("expected trailing new lines to be removed, got %q", msg)
+	}
+	if _, err := readMessage(strings.NewReader("\xff\xfe")); err == nil {
+		t.Error("no error raised on invalid UTF-8")
+	}
+}
+
+func TestBuildMessages(t *testing.T) {
+	// short message is passed as is
+	if msgs, err := buildMessages("hoge", false, 10, overflowError); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(msgs, []string{"hoge"}) {
+		t.Errorf("expected [hoge], got %q", msgs)
+	}
+
+	// code block
+	if msgs, err := buildMessages("hoge", true, 20, overflowError); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(msgs, []string{"```\nhoge\n```"}) {
+		t.Errorf("expected code block, got %q", msgs)
+	}
+
+	// too long
+	if _, err := buildMessages("hogehoge", false, 5, overflowError); err == nil {
+		t.Error("no error raised on a message over the limit")
+	}
+	if _, err := buildMessages("", false, 5, overflowError); err == nil {
+		t.Error("no error raised on an empty message")
+	}
+
+	// truncate counting characters, not bytes
+	text := strings.Repeat("あ", 30)
+	if msgs, err := buildMessages(text, false, 20, overflowTruncate); err != nil {
+		t.Error(err)
+	} else if len(msgs) != 1 || !strings.HasSuffix(msgs[0], truncatedMarker) || len([]rune(msgs[0])) != 20 {
+		t.Errorf("expected a truncated message of 20 characters, got %q", msgs)
+	}
+
+	// split at new lines
+	text = "aaaa\nbbbb\ncccc\ndddddddddddd"
+	expected := []string{"aaaa\nbbbb", "cccc", "dddddddddd", "dd"}
+	if msgs, err := buildMessages(text, false, 10, overflowSplit); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(msgs, expected) {
+		t.Errorf("expected %q, got %q", expected, msgs)
+	}
+}
+
+func TestReadBlocks(t *testing.T) {
+	f, err := ioutil.TempFile("", "blocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`[{"type":"header","text":{"type":"plain_text","text":"Release"}},{"type":"divider"}]`)
+	f.Close()
+
+	blocks, err := readBlocks(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 || blocks[0].BlockType() != "header" {
+		t.Errorf("unexpected blocks %v", blocks)
+	}
+
+	ioutil.WriteFile(f.Name(), []byte(`[{"type":"headr"}]`), 0600)
+	if _, err := readBlocks(f.Name()); err == nil {
+		t.Error("no error raised on unknown block type")
+	}
+}
+
+func TestBuildAttachment(t *testing.T) {
+	var fields fieldFlags
+	if err := fields.Set("env=prod"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fields.Set("query=a=b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fields.Set("novalue"); err == nil {
+		t.Error("no error raised on a field without =")
+	}
+	if fields[1].Title != "query" || fields[1].Value != "a=b" {
+		t.Errorf("value should be after the first =, got %+v", fields[1])
+	}
+
+	a, err := buildAttachment("Deploy failed\nsee logs", "danger", "https://ci.example.com", fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Title != "Deploy failed" || a.Text != "see logs" || a.TitleLink != "https://ci.example.com" || len(a.Fields) != 2 {
+		t.Errorf("unexpected attachment %+v", a)
+	}
+
+	a, err = buildAttachment("Deploy succeeded\nv1.2.0", "good", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Title != "" || a.Text != "Deploy succeeded\nv1.2.0" {
+		t.Errorf("whole text should be the body without title link, got %+v", a)
+	}
+
+	if _, err := buildAttachment("hoge", "blue", "", nil); err == nil {
+		t.Error("no error raised on unknown color")
+	}
+}
+
+func TestParseMessageArgs(t *testing.T) {
+	defer func() { *messageCode = false }()
+
+	expected, err := buildMessages("hello world", true, maxMessageLength, overflowError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"hello world", "--code"}, {"--code", "hello world"}} {
+		*messageCode = false
+		if messages, err := parseMessageArgs(args); err != nil || !reflect.DeepEqual(messages, expected) {
+			t.Errorf("%q: expected %q, got %q, %v", args, expected, messages, err)
+		}
+	}
+
+	for _, args := range [][]string{{"hello", "--code", "world"}, {"--code", "hello", "world"}} {
+		if _, err := parseMessageArgs(args); err == nil {
+			t.Errorf("%q: no error raised on extra arguments", args)
+		}
+	}
+}
@@ -1,23 +1,46 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"time"
 
 	"github.com/manifoldco/promptui"
 	"github.com/matthewlujp/slack-cmd-client/src/slack"
 )
 
-// Add given token to token file and enable user to send message and upload file to a workspace.
-// Token file is created in the home directory.
-// If a token file does not exist in the home directory, a new file is created.
-func registerToken(token string) error {
+var errNotInteractive = errors.New("stdin is not a terminal")
+
+// confirm asks a yes/no question on the terminal and reports whether the answer is y.
+// It answers yes without asking when yes is true, and fails when stdin is not a terminal rather than waiting for an answer.
+func confirm(question string, yes bool) (bool, error) {
+	if yes {
+		return true, nil
+	}
+	if !isTerminal(os.Stdin) {
+		return false, fmt.Errorf("%w, cannot confirm \"%s\"", errNotInteractive, question)
+	}
+	fmt.Printf("%s  y/n ", question)
+	var ans string
+	fmt.Scan(&ans)
+	return ans == "y", nil
+}
+
+// Add given token to the config file and enable user to send message and upload file to a workspace.
+// The config file is the one getConfigFilePath resolves from --config, SLACK_CLI_CONFIG, or XDG_CONFIG_HOME,
+// and it is created along with its directory if it does not exist.
+// A bot token and a user token of a workspace are kept apart, and a token of the same kind is replaced.
+// Confirmation is skipped when yes is true.
+func registerToken(ctx context.Context, token string, yes bool) error {
 	c, err := slack.NewClient(token, logger)
 	if err != nil {
 		return err
 	}
 
-	workspace, err := c.ObtainWorkspaceInfo()
+	workspace, err := c.ObtainWorkspaceInfoContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -27,41 +50,50 @@ func registerToken(token string) error {
 		logger.Printf("[registerToken] load config failed, %s", err)
 		return err
 	}
+	t := tokenConfig{Kind: tokenKind(token), Token: token, Scopes: workspace.Scopes}
 	// check whether tha workspace has already been registered
-	registeredID := -1
-	for i := range conf.Workspaces {
-		if conf.Workspaces[i].ID == workspace.ID {
-			registeredID = i
-		}
-	}
-	if registeredID > -1 { // has been registered
-		fmt.Printf("Are you sure to overwrite workspace %s ?  y/n ", workspace.Name)
-		var ans string
-		fmt.Scan(&ans)
-		if ans != "y" {
-			fmt.Println("Operation cancelled.")
-			return nil
+	question := fmt.Sprintf("Are you sure to add workspace %s ?", workspace.Name)
+	if w := registeredWorkspace(conf, workspace.ID); w != nil {
+		if err := w.checkReplaceable(t.Kind); err != nil {
+			return err
 		}
-		conf.Workspaces[registeredID].Name = workspace.Name
-		conf.Workspaces[registeredID].Domain = workspace.Domain
-		conf.Workspaces[registeredID].Token = workspace.Token
-	} else { // is not registered
-		fmt.Printf("Are you sure to add workspace %s ?  y/n ", workspace.Name)
-		var ans string
-		fmt.Scan(&ans)
-		if ans != "y" {
-			fmt.Println("Operation cancelled.")
-			return nil
+		question = fmt.Sprintf("Are you sure to add %s token to workspace %s ?", t.Kind, workspace.Name)
+		if w.findToken(t.Kind) != nil {
+			question = fmt.Sprintf("Are you sure to overwrite %s token of workspace %s ?", t.Kind, workspace.Name)
 		}
-		conf.Workspaces = append(conf.Workspaces, *workspace)
 	}
-
-	// set current context workspace if not set
-	if conf.CurrentWorkspaceToken == "" {
-		conf.CurrentWorkspaceToken = token
+	ok, err := confirm(question, yes)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Operation cancelled.")
+		return nil
 	}
 
-	if err := saveConfig(conf); err != nil {
+	// the config is read again under the lock since another process may have changed it while confirming
+	if err := updateConfig(func(conf *config) error {
+		if w := registeredWorkspace(conf, workspace.ID); w != nil {
+			w.Name = workspace.Name
+			w.Domain = workspace.Domain
+			if err := w.setToken(t); err != nil {
+				return err
+			}
+		} else {
+			conf.Workspaces = append(conf.Workspaces, workspaceConfig{
+				ID:     workspace.ID,
+				Name:   workspace.Name,
+				Domain: workspace.Domain,
+				Tokens: []tokenConfig{t},
+			})
+		}
+
+		// set current context workspace if not set
+		if conf.CurrentWorkspace == "" {
+			conf.CurrentWorkspace = workspace.ID
+		}
+		return nil
+	}); err != nil {
 		logger.Printf("[registerToken] saving config failed, %s", err)
 		return err
 	}
@@ -70,24 +102,46 @@ func registerToken(token string) error {
 	return nil
 }
 
-// switchWorkspace shows a list of registered workspaces and let user choose one.
-func switchWorkspace() error {
+// switchWorkspace switches to a workspace whose alias, name, ID, or domain is key.
+// When key is empty, it shows a list of registered workspaces and let user choose one, which needs a terminal.
+func switchWorkspace(key string) error {
+	if key != "" {
+		var name string
+		if err := updateConfig(func(conf *config) error {
+			w, err := findWorkspace(conf, key)
+			if err != nil {
+				return err
+			}
+			conf.CurrentWorkspace, name = w.ID, w.Name
+			return nil
+		}); err != nil {
+			logger.Printf("[switchWorkspace] failed in saveing new context workspace, %s", err)
+			return err
+		}
+		fmt.Printf("Switched to %s", name)
+		return nil
+	}
+
 	// list registered workspaces
 	conf := &config{}
 	if err := loadConfig(conf); err != nil {
 		logger.Printf("[switchWorkspace] loading config failed, %s", err)
 		return err
 	}
+
 	if len(conf.Workspaces) < 1 {
 		fmt.Println("No workspace is registered.")
 		return nil
 	}
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("%w, give a workspace name or ID to switch", errNotInteractive)
+	}
 
 	workspaceNames := make([]string, 0, len(conf.Workspaces))
 	var currentWorkspaceName string
 	for _, w := range conf.Workspaces {
 		workspaceNames = append(workspaceNames, w.Name)
-		if w.Token == conf.CurrentWorkspaceToken {
+		if w.ID == conf.CurrentWorkspace {
 			currentWorkspaceName = w.Name
 		}
 	}
@@ -98,12 +152,19 @@ func switchWorkspace() error {
 	selectedID, result, err := prompt.Run()
 	if err != nil {
 		logger.Printf("[switchWorkspace] selection went wrong")
+		return err
 	}
 
-	// switch current workspace token
-	conf.CurrentWorkspaceToken = conf.Workspaces[selectedID].Token
-	if err := saveConfig(conf); err != nil {
-		logger.Printf("[switchWorkspace] failed in saveing new context token")
+	// switch current workspace, which may have been removed by another process while selecting
+	selected := conf.Workspaces[selectedID].ID
+	if err := updateConfig(func(conf *config) error {
+		if registeredWorkspace(conf, selected) == nil {
+			return fmt.Errorf("%w: %s", errWorkspaceNotRegistered, result)
+		}
+		conf.CurrentWorkspace = selected
+		return nil
+	}); err != nil {
+		logger.Printf("[switchWorkspace] failed in saveing new context workspace, %s", err)
 		return err
 	}
 
@@ -111,7 +172,7 @@ func switchWorkspace() error {
 	return nil
 }
 
-func listChannels() error {
+func listChannels(ctx context.Context) error {
 	workspace, token, err := getCurrentWorkspace()
 	if err != nil {
 		logger.Printf("[listChannels] getting current workspace name and token failed, %s", err)
@@ -123,13 +184,25 @@ func listChannels() error {
 		logger.Printf("[listChannels] building new client failed, %s", err)
 		return err
 	}
-	channels, err := c.CollectChannels()
+	channels, err := c.CollectChannelsContext(ctx)
 	if err != nil {
 		logger.Printf("[listChannels] collecting channels failed, %s", err)
 		return err
 	}
 
-	fmt.Printf("Channels you join in workspace %s are,\n", workspace)
+	if *outputFormat != outputText {
+		records := make([]channelRecord, 0, len(channels))
+		for _, ch := range channels {
+			records = append(records, newChannelRecord(ch))
+		}
+		return renderOutput(os.Stdout, *outputFormat, *outputTmpl, records)
+	}
+
+	if workspace == "" {
+		fmt.Println("Channels you join are,")
+	} else {
+		fmt.Printf("Channels you join in workspace %s are,\n", workspace)
+	}
 	for _, ch := range channels {
 		var desc string
 		if ch.IsDirectMessage {
@@ -143,7 +216,39 @@ func listChannels() error {
 	return nil
 }
 
-func sendMessage(channelIDOrName, message string) error {
+// channelRecord is a channel rendered by list in structured output formats
+type channelRecord struct {
+	ID          string `json:"id" yaml:"id"`
+	Name        string `json:"name" yaml:"name"`
+	IsPrivate   bool   `json:"is_private" yaml:"is_private"`
+	IsIM        bool   `json:"is_im" yaml:"is_im"`
+	MemberCount int    `json:"member_count" yaml:"member_count"`
+	Purpose     string `json:"purpose" yaml:"purpose"`
+}
+
+func newChannelRecord(ch slack.Channel) channelRecord {
+	return channelRecord{
+		ID:          ch.ID,
+		Name:        ch.Name,
+		IsPrivate:   ch.IsPrivate,
+		IsIM:        ch.IsDirectMessage,
+		MemberCount: ch.MemberCount(),
+		Purpose:     ch.Purpose.Value,
+	}
+}
+
+// postOptions holds how messages are posted by sendMessage
+type postOptions struct {
+	threadTS    string             // reply in the thread of this message if not empty
+	broadcast   bool               // show replies in the channel as well
+	printTS     bool               // print only timestamps of posted messages to stdout
+	blocks      slack.Blocks       // lay out messages with blocks if not empty
+	attachments []slack.Attachment // add attachments to messages if not empty
+}
+
+// sendMessage posts messages to a channel in order.
+// Long text is passed as several messages when split by buildMessages.
+func sendMessage(ctx context.Context, channelIDOrName string, messages []string, opts postOptions) error {
 	_, token, err := getCurrentWorkspace()
 	if err != nil {
 		logger.Printf("[sendMessage] retrieving toke from config file failed, %s", err)
@@ -155,23 +260,191 @@ func sendMessage(channelIDOrName, message string) error {
 		return err
 	}
 
-	channelName, channelID, err := toChannelNameAndID(channelIDOrName, c)
+	channelName, channelID, err := toChannelNameAndID(ctx, channelIDOrName, c)
 	if err != nil {
 		logger.Printf("[sendMessage] %s", err)
 		return err
 	}
 
-	fmt.Printf("Sending message to %s\n", channelName)
+	// keep stdout for timestamps so that scripts can capture them
+	status := io.Writer(os.Stdout)
+	if opts.printTS {
+		status = os.Stderr
+	}
+	var messageOptions []slack.MessageOption
+	if len(opts.blocks) > 0 {
+		messageOptions = append(messageOptions, slack.WithBlocks(opts.blocks))
+	}
+	if len(opts.attachments) > 0 {
+		messageOptions = append(messageOptions, slack.WithAttachments(opts.attachments...))
+	}
+	if opts.threadTS != "" {
+		messageOptions = append(messageOptions, slack.InThread(opts.threadTS))
+		if opts.broadcast {
+			messageOptions = append(messageOptions, slack.Broadcast())
+		}
+	}
+
+	// the workspace to remember the messages in for edit and delete, which is unknown with a token given by SLACK_TOKEN
+	workspaceID, idErr := getCurrentWorkspaceID()
+
+	fmt.Fprintf(status, "Sending message to %s\n", channelName)
 	// send message
-	if err := c.SendMessage(channelID, message); err != nil {
-		logger.Printf("[sendMessage] send request failed, %s", err)
+	for i, message := range messages {
+		ts, err := c.SendMessageContext(ctx, channelID, message, messageOptions...)
+		if err != nil {
+			logger.Printf("[sendMessage] send request failed, %s", err)
+			return err
+		}
+		if opts.printTS {
+			fmt.Println(ts)
+		}
+		// remember the message for edit and delete
+		if idErr == nil {
+			if err := recordMessage(workspaceID, channelID, ts); err != nil {
+				logger.Printf("[sendMessage] recording sent message failed, %s", err)
+			}
+		}
+		if len(messages) > 1 {
+			fmt.Fprintf(status, "Sent part %d of %d\n", i+1, len(messages))
+		}
+	}
+	fmt.Fprintln(status, "Message successfully sent")
+	return nil
+}
+
+// showHistory prints messages posted to a channel within since, or all if since is zero, in chronological order.
+// At most limit latest messages are printed, or all if limit is zero.
+func showHistory(ctx context.Context, channelIDOrName string, since time.Duration, limit int) error {
+	_, token, err := getCurrentWorkspace()
+	if err != nil {
+		logger.Printf("[showHistory] retrieving toke from config file failed, %s", err)
+		return err
+	}
+	c, err := slack.NewClient(token, logger)
+	if err != nil {
+		logger.Printf("[showHistory] building client failed, %s", err)
+		return err
+	}
+	_, channelID, members, err := toChannelAndMembers(ctx, channelIDOrName, c)
+	if err != nil {
+		logger.Printf("[showHistory] %s", err)
 		return err
 	}
-	fmt.Println("Message successfully sent")
+
+	var oldest time.Time
+	if since > 0 {
+		oldest = time.Now().Add(-since)
+	}
+	messages, err := c.HistoryContext(ctx, channelID, oldest, time.Time{}, limit)
+	if err != nil {
+		logger.Printf("[showHistory] reading history failed, %s", err)
+		return err
+	}
+
+	// history is returned from the newest
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return renderMessages(os.Stdout, *outputFormat, *outputTmpl, messages, members)
+}
+
+// showThread prints a message with timestamp ts and its replies in order.
+func showThread(ctx context.Context, channelIDOrName, ts string) error {
+	_, token, err := getCurrentWorkspace()
+	if err != nil {
+		logger.Printf("[showThread] retrieving toke from config file failed, %s", err)
+		return err
+	}
+	c, err := slack.NewClient(token, logger)
+	if err != nil {
+		logger.Printf("[showThread] building client failed, %s", err)
+		return err
+	}
+	_, channelID, members, err := toChannelAndMembers(ctx, channelIDOrName, c)
+	if err != nil {
+		logger.Printf("[showThread] %s", err)
+		return err
+	}
+
+	messages, err := c.RepliesContext(ctx, channelID, ts)
+	if err != nil {
+		logger.Printf("[showThread] reading replies failed, %s", err)
+		return err
+	}
+	return renderMessages(os.Stdout, *outputFormat, *outputTmpl, messages, members)
+}
+
+// editMessage replaces text of a message.
+// If last is true, the latest message sent by this tool is edited, which is searched in the channel if given.
+func editMessage(ctx context.Context, channelIDOrName, ts, text string, last bool) error {
+	c, channelID, ts, err := resolveSentMessage(ctx, channelIDOrName, ts, last)
+	if err != nil {
+		logger.Printf("[editMessage] %s", err)
+		return err
+	}
+	if err := c.UpdateMessageContext(ctx, channelID, ts, text); err != nil {
+		logger.Printf("[editMessage] update request failed, %s", err)
+		return err
+	}
+	fmt.Println("Message successfully edited")
 	return nil
 }
 
-func uploadFile(channelIDOrName, filepath, title, comment string) error {
+// deleteMessage deletes a message.
+// If last is true, the latest message sent by this tool is deleted, which is searched in the channel if given.
+func deleteMessage(ctx context.Context, channelIDOrName, ts string, last bool) error {
+	c, channelID, ts, err := resolveSentMessage(ctx, channelIDOrName, ts, last)
+	if err != nil {
+		logger.Printf("[deleteMessage] %s", err)
+		return err
+	}
+	if err := c.DeleteMessageContext(ctx, channelID, ts); err != nil {
+		logger.Printf("[deleteMessage] delete request failed, %s", err)
+		return err
+	}
+	if workspaceID, err := getCurrentWorkspaceID(); err == nil {
+		if err := forgetMessage(workspaceID, channelID, ts); err != nil {
+			logger.Printf("[deleteMessage] updating history failed, %s", err)
+		}
+	}
+	fmt.Println("Message successfully deleted")
+	return nil
+}
+
+// resolveSentMessage returns a client, channel ID and timestamp of a message to edit or delete.
+func resolveSentMessage(ctx context.Context, channelIDOrName, ts string, last bool) (*slack.Client, string, string, error) {
+	_, token, err := getCurrentWorkspace()
+	if err != nil {
+		return nil, "", "", err
+	}
+	c, err := slack.NewClient(token, logger)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var channelID string
+	if channelIDOrName != "" {
+		if _, channelID, err = toChannelNameAndID(ctx, channelIDOrName, c); err != nil {
+			return nil, "", "", err
+		}
+	}
+	if !last {
+		return c, channelID, ts, nil
+	}
+
+	workspaceID, err := getCurrentWorkspaceID()
+	if err != nil {
+		return nil, "", "", err
+	}
+	m, err := lastMessage(workspaceID, channelID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return c, m.ChannelID, m.TS, nil
+}
+
+func uploadFile(ctx context.Context, channelIDOrName, filepath, name, title, comment, threadTS string, broadcast, spool, showProgress bool) error {
 	_, token, err := getCurrentWorkspace()
 	if err != nil {
 		logger.Printf("[uploadFile] retrieving toke from config file failed, %s", err)
@@ -183,21 +456,47 @@ func uploadFile(channelIDOrName, filepath, title, comment string) error {
 		return err
 	}
 
-	channelName, channelID, err := toChannelNameAndID(channelIDOrName, c)
+	channelName, channelID, err := toChannelNameAndID(ctx, channelIDOrName, c)
 	if err != nil {
 		logger.Printf("[uploadFile] %s", err)
 		return err
 	}
-	fmt.Printf("Uploading %s to %s\n", filepath, channelName)
 
-	uploadOptions := make(map[string]string)
-	if title != "" {
-		uploadOptions["title"] = title
+	// read from stdin when file path is "-"
+	var r io.Reader
+	if filepath == "-" {
+		r = os.Stdin
+		if name == "" {
+			name = "stdin"
+		}
+		fmt.Printf("Uploading stdin to %s\n", channelName)
+	} else {
+		f, err := os.Open(filepath)
+		if err != nil {
+			logger.Printf("[uploadFile] opening file failed, %s", err)
+			return err
+		}
+		defer f.Close()
+		r = f
+		if name == "" {
+			if info, err := f.Stat(); err == nil {
+				name = info.Name()
+			}
+		}
+		fmt.Printf("Uploading %s to %s\n", filepath, channelName)
 	}
-	if comment != "" {
-		uploadOptions["initial_comment"] = comment
+
+	uploadOptions := slack.UploadOptions{
+		Title:          title,
+		InitialComment: comment,
+		ThreadTS:       threadTS,
+		Broadcast:      broadcast,
+		Spool:          spool,
 	}
-	if err := c.UploadFile(channelID, filepath, uploadOptions); err != nil {
+	if showProgress {
+		uploadOptions.Progress = newProgressPrinter(os.Stdout, isTerminal(os.Stdout)).update
+	}
+	if err := c.UploadReader(ctx, channelID, name, r, uploadOptions); err != nil {
 		logger.Printf("[uploadFile] uploading failed, %s", err)
 		return err
 	}
@@ -205,11 +504,18 @@ func uploadFile(channelIDOrName, filepath, title, comment string) error {
 	return nil
 }
 
-func toChannelNameAndID(channelIDOrName string, c *slack.Client) (string, string, error) {
-	channels, err := c.CollectChannels()
+func toChannelNameAndID(ctx context.Context, channelIDOrName string, c *slack.Client) (string, string, error) {
+	name, id, _, err := toChannelAndMembers(ctx, channelIDOrName, c)
+	return name, id, err
+}
+
+// toChannelAndMembers is toChannelNameAndID which also returns members of the workspace obtained along with channels,
+// so that user names are resolved without listing members again.
+func toChannelAndMembers(ctx context.Context, channelIDOrName string, c *slack.Client) (string, string, slack.Members, error) {
+	channels, members, err := c.CollectChannelsAndMembersContext(ctx)
 	if err != nil {
 		logger.Printf("[toChannelID] collecting channel failed, %s", err)
-		return "", "", err
+		return "", "", nil, err
 	}
 	var targetChannelID string
 	var targetChannelName string
@@ -221,7 +527,7 @@ func toChannelNameAndID(channelIDOrName string, c *slack.Client) (string, string
 	}
 	if targetChannelID == "" {
 		logger.Printf("[toChannelNameAndID] channel %s does not exist", channelIDOrName)
-		return "", "", errors.New("invalid channel name or id")
+		return "", "", nil, errors.New("invalid channel name or id")
 	}
-	return targetChannelName, targetChannelID, nil
+	return targetChannelName, targetChannelID, members, nil
 }
@@ -1 +1,52 @@
 package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestConfirm(t *testing.T) {
+	if ok, err := confirm("Are you sure?", true); err != nil || !ok {
+		t.Errorf("expected to be confirmed with yes, got %t, %v", ok, err)
+	}
+	if isTerminal(os.Stdin) {
+		t.Skip("stdin is a terminal")
+	}
+	if _, err := confirm("Are you sure?", false); !errors.Is(err, errNotInteractive) {
+		t.Errorf("expected errNotInteractive, got %v", err)
+	}
+}
+
+func TestSwitchWorkspace(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	conf := &config{
+		CurrentWorkspace: "000a",
+		Workspaces: []workspaceConfig{
+			{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-a"}}},
+			{ID: "000b", Name: "workspace B", Domain: "hoge-foo", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-b"}}},
+		},
+	}
+	if err := saveConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := switchWorkspace("000b"); err != nil {
+		t.Fatal(err)
+	}
+	if name, _, err := getCurrentWorkspace(); err != nil || name != "workspace B" {
+		t.Errorf("expected to switch to workspace B, got %s, %v", name, err)
+	}
+	if err := switchWorkspace("workspace C"); !errors.Is(err, errWorkspaceNotRegistered) {
+		t.Errorf("expected errWorkspaceNotRegistered, got %v", err)
+	}
+
+	if isTerminal(os.Stdin) {
+		t.Skip("stdin is a terminal")
+	}
+	if err := switchWorkspace(""); !errors.Is(err, errNotInteractive) {
+		t.Errorf("expected errNotInteractive, got %v", err)
+	}
+}
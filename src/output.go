@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// output formats of read commands
+const (
+	outputText     = "text" // human readable output of each command
+	outputMarkdown = "markdown"
+	outputJSON     = "json"
+	outputYAML     = "yaml"
+	outputCSV      = "csv"
+	outputTable    = "table"
+	outputTemplate = "template"
+)
+
+// global flags of output formats, see splitGlobalFlags
+var (
+	outputFormat = globalCmd.String("output", outputText, "output format of read commands, one of text, json, yaml, csv, table, and template")
+	outputTmpl   = globalCmd.String("template", "", "Go text/template executed for each item with --output template, such as '{{.ID}} {{.Name}}'")
+)
+
+// checkOutput returns an error if format is neither a common output format nor one of extra formats of a command.
+func checkOutput(format, tmpl string, extra ...string) error {
+	switch format {
+	case outputText, outputJSON, outputYAML, outputCSV, outputTable:
+		return nil
+	case outputTemplate:
+		if tmpl == "" {
+			return fmt.Errorf("--output template requires --template")
+		}
+		_, err := template.New("output").Parse(tmpl)
+		return err
+	}
+	for _, e := range extra {
+		if format == e {
+			return nil
+		}
+	}
+	formats := append([]string{outputText}, extra...)
+	formats = append(formats, outputJSON, outputYAML, outputCSV, outputTable, outputTemplate)
+	return fmt.Errorf("unknown output format %q, which should be one of %s", format, strings.Join(formats, ", "))
+}
+
+// renderOutput writes records, which is a slice of structs, in one of json, yaml, csv, table, and template formats.
+// Columns of csv and table are fields of the struct named after their json tags.
+func renderOutput(w io.Writer, format, tmpl string, records interface{}) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case outputYAML:
+		data, err := yaml.Marshal(records)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case outputTemplate:
+		t, err := template.New("output").Parse(tmpl)
+		if err != nil {
+			return err
+		}
+		v := reflect.ValueOf(records)
+		for i := 0; i < v.Len(); i++ {
+			if err := t.Execute(w, v.Index(i).Interface()); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputCSV:
+		header, rows := tabulate(records)
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		cw.WriteAll(rows)
+		return cw.Error()
+	case outputTable:
+		header, rows := tabulate(records)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for i := range header {
+			header[i] = strings.ToUpper(header[i])
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range rows {
+			for i := range row {
+				// keep a cell in one line
+				row[i] = strings.Replace(row[i], "\n", " ", -1)
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+	return checkOutput(format, tmpl)
+}
+
+// tabulate turns a slice of structs into a header and rows of their fields.
+func tabulate(records interface{}) ([]string, [][]string) {
+	v := reflect.ValueOf(records)
+	t := v.Type().Elem()
+
+	var header []string
+	var indices []int
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "-" || t.Field(i).PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		header = append(header, name)
+		indices = append(indices, i)
+	}
+
+	rows := make([][]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		row := make([]string, 0, len(indices))
+		for _, j := range indices {
+			row = append(row, cellString(v.Index(i).Field(j)))
+		}
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+// cellString formats a field value in a cell, joining elements of slices with ";".
+func cellString(v reflect.Value) string {
+	switch x := v.Interface().(type) {
+	case time.Time:
+		return x.Format(time.RFC3339)
+	case fmt.Stringer:
+		return x.String()
+	}
+	if v.Kind() == reflect.Slice {
+		cells := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cells = append(cells, cellString(v.Index(i)))
+		}
+		return strings.Join(cells, ";")
+	}
+	return fmt.Sprint(v.Interface())
+}
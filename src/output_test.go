@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheckOutput(t *testing.T) {
+	if err := checkOutput(outputCSV, ""); err != nil {
+		t.Error(err)
+	}
+	if err := checkOutput(outputMarkdown, ""); err == nil {
+		t.Error("no error raised on markdown, which is not a common format")
+	}
+	if err := checkOutput(outputMarkdown, "", outputMarkdown); err != nil {
+		t.Error(err)
+	}
+	if err := checkOutput(outputTemplate, ""); err == nil {
+		t.Error("no error raised on template format without template")
+	}
+	if err := checkOutput(outputTemplate, "{{.Name"); err == nil {
+		t.Error("no error raised on broken template")
+	}
+}
+
+func TestRenderOutput(t *testing.T) {
+	records := []channelRecord{
+		{ID: "C1", Name: "general", MemberCount: 10, Purpose: "hoge, foo"},
+		{ID: "D1", Name: "taro", IsIM: true, MemberCount: 2},
+	}
+	cases := []struct {
+		format, tmpl, expected string
+	}{
+		{outputCSV, "", "id,name,is_private,is_im,member_count,purpose\nC1,general,false,false,10,\"hoge, foo\"\nD1,taro,false,true,2,\n"},
+		{outputTable, "", "ID  NAME     IS_PRIVATE  IS_IM  MEMBER_COUNT  PURPOSE\nC1  general  false       false  10            hoge, foo\nD1  taro     false       true   2             \n"},
+		{outputTemplate, "{{.Name}} ({{.ID}})", "general (C1)\ntaro (D1)\n"},
+		{outputYAML, "", "- id: C1\n  name: general\n  is_private: false\n  is_im: false\n  member_count: 10\n  purpose: hoge, foo\n- id: D1\n  name: taro\n  is_private: false\n  is_im: true\n  member_count: 2\n  purpose: \"\"\n"},
+	}
+	for _, c := range cases {
+		var b bytes.Buffer
+		if err := renderOutput(&b, c.format, c.tmpl, records); err != nil {
+			t.Errorf("%s: %s", c.format, err)
+			continue
+		}
+		if b.String() != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.format, c.expected, b.String())
+		}
+	}
+}
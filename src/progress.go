@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+const (
+	progressBarWidth = 30
+	// progressLineInterval is how often a progress line is printed when output is not a terminal
+	progressLineInterval = time.Second
+)
+
+// progressPrinter renders upload progress as a bar on a terminal, or as machine-readable lines otherwise.
+type progressPrinter struct {
+	w        io.Writer
+	terminal bool
+	last     time.Time
+	done     bool
+}
+
+func newProgressPrinter(w io.Writer, terminal bool) *progressPrinter {
+	return &progressPrinter{w: w, terminal: terminal}
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// update is passed to slack.UploadOptions as a progress callback
+func (p *progressPrinter) update(progress slack.UploadProgress) {
+	finished := progress.Total > 0 && progress.Sent >= progress.Total
+	if !finished && time.Since(p.last) < p.interval() {
+		return
+	}
+	p.last = time.Now()
+
+	if p.terminal {
+		fmt.Fprintf(p.w, "\r%s", formatProgressBar(progress))
+		if finished && !p.done {
+			fmt.Fprintln(p.w)
+		}
+	} else if !p.done {
+		fmt.Fprintln(p.w, formatProgressLine(progress))
+	}
+	p.done = finished
+}
+
+func (p *progressPrinter) interval() time.Duration {
+	if p.terminal {
+		return 100 * time.Millisecond
+	}
+	return progressLineInterval
+}
+
+// formatProgressBar returns a line such as "[#######-------]  45% 12.0 MB / 26.7 MB  1.2 MB/s"
+func formatProgressBar(p slack.UploadProgress) string {
+	if p.Total <= 0 {
+		return fmt.Sprintf("%s  %s/s", formatBytes(p.Sent), formatBytes(int64(p.Throughput)))
+	}
+	ratio := float64(p.Sent) / float64(p.Total)
+	if ratio > 1 {
+		ratio = 1
+	}
+	filled := int(ratio * progressBarWidth)
+	return fmt.Sprintf("[%s%s] %3.0f%% %s / %s  %s/s",
+		strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled),
+		ratio*100, formatBytes(p.Sent), formatBytes(p.Total), formatBytes(int64(p.Throughput)))
+}
+
+// formatProgressLine returns a line such as "progress sent=12000000 total=26700000 bytes_per_sec=1200000"
+func formatProgressLine(p slack.UploadProgress) string {
+	return fmt.Sprintf("progress sent=%d total=%d bytes_per_sec=%.0f", p.Sent, p.Total, p.Throughput)
+}
+
+// formatBytes returns a human readable size such as "1.2 MB"
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:          "0 B",
+		999:        "999 B",
+		1000:       "1.0 kB",
+		1200000:    "1.2 MB",
+		3000000000: "3.0 GB",
+	}
+	for n, expected := range cases {
+		if s := formatBytes(n); s != expected {
+			t.Errorf("%d bytes expected %s, got %s", n, expected, s)
+		}
+	}
+}
+
+func TestProgressPrinter(t *testing.T) {
+	// machine-readable lines when not a terminal
+	bf := new(bytes.Buffer)
+	p := newProgressPrinter(bf, false)
+	p.update(slack.UploadProgress{Sent: 10, Total: 100, Throughput: 5})
+	p.update(slack.UploadProgress{Sent: 50, Total: 100, Throughput: 5}) // skipped within the interval
+	p.update(slack.UploadProgress{Sent: 100, Total: 100, Throughput: 5})
+	expected := "progress sent=10 total=100 bytes_per_sec=5\nprogress sent=100 total=100 bytes_per_sec=5\n"
+	if bf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, bf.String())
+	}
+
+	// bar on a terminal
+	bf.Reset()
+	p = newProgressPrinter(bf, true)
+	p.update(slack.UploadProgress{Sent: 100, Total: 100, Throughput: 5})
+	if !strings.Contains(bf.String(), "["+strings.Repeat("#", progressBarWidth)+"] 100%") {
+		t.Errorf("complete progress bar expected, got %q", bf.String())
+	}
+}
@@ -0,0 +1,80 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// Colors of the side bar of an attachment, besides hex codes such as "#439FE0"
+const (
+	ColorGood    = "good"
+	ColorWarning = "warning"
+	ColorDanger  = "danger"
+)
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Attachment is a legacy secondary content of a message, shown with a colored side bar
+// See https://api.slack.com/reference/messaging/attachments
+type Attachment struct {
+	Fallback   string            `json:"fallback,omitempty"` // plain text shown in notifications
+	Color      string            `json:"color,omitempty"`    // good, warning, danger, or a hex code
+	Pretext    string            `json:"pretext,omitempty"`  // text shown above the attachment
+	Title      string            `json:"title,omitempty"`
+	TitleLink  string            `json:"title_link,omitempty"`
+	Text       string            `json:"text,omitempty"`
+	Fields     []AttachmentField `json:"fields,omitempty"`
+	Footer     string            `json:"footer,omitempty"`
+	TS         int64             `json:"ts,omitempty"`        // unix time shown in the footer
+	MarkdownIn []string          `json:"mrkdwn_in,omitempty"` // names of fields formatted in mrkdwn, such as "text"
+}
+
+// AttachmentField is a titled value shown in a table in an attachment
+type AttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short,omitempty"` // shown side by side with other short fields
+}
+
+// Validate checks the color and that the attachment has something to show.
+func (a *Attachment) Validate() error {
+	switch a.Color {
+	case "", ColorGood, ColorWarning, ColorDanger:
+	default:
+		if !hexColor.MatchString(a.Color) {
+			return fmt.Errorf("color should be good, warning, danger, or a hex code like #439FE0, got %q", a.Color)
+		}
+	}
+	if a.Fallback == "" && a.Pretext == "" && a.Title == "" && a.Text == "" && len(a.Fields) == 0 {
+		return errors.New("attachment is empty")
+	}
+	if a.TitleLink != "" && a.Title == "" {
+		return errors.New("title_link requires title")
+	}
+	for i, f := range a.Fields {
+		if f.Title == "" && f.Value == "" {
+			return fmt.Errorf("field %d is empty", i)
+		}
+	}
+	return nil
+}
+
+// WithAttachments returns an option which adds attachments to a message.
+func WithAttachments(attachments ...Attachment) MessageOption {
+	return func(v url.Values) error {
+		for i := range attachments {
+			if err := attachments[i].Validate(); err != nil {
+				return fmt.Errorf("attachment %d: %s", i, err)
+			}
+		}
+		data, err := json.Marshal(attachments)
+		if err != nil {
+			return err
+		}
+		v.Set("attachments", string(data))
+		return nil
+	}
+}
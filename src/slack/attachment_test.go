@@ -0,0 +1,63 @@
+package slack_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+func TestSendMessageWithAttachments(t *testing.T) {
+	var received []slack.Attachment
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = nil
+		json.Unmarshal([]byte(r.FormValue("attachments")), &received)
+		json.NewEncoder(w).Encode(&struct {
+			Ok bool   `json:"ok"`
+			TS string `json:"ts"`
+		}{Ok: true, TS: targetTS})
+	}))
+	defer s.Close()
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL))
+
+	attachment := slack.Attachment{
+		Color:     slack.ColorDanger,
+		Title:     "Deploy failed",
+		TitleLink: "https://ci.example.com/builds/1",
+		Fields:    []slack.AttachmentField{{Title: "env", Value: "prod", Short: true}},
+		Footer:    "ci",
+		TS:        1500000000,
+	}
+	if _, err := client.SendMessage(targetChannel, "", slack.WithAttachments(attachment)); err != nil {
+		t.Fatalf("sending message failed, %s", err)
+	}
+	if !reflect.DeepEqual(received, []slack.Attachment{attachment}) {
+		t.Errorf("expected %v, got %v", attachment, received)
+	}
+}
+
+func TestAttachmentValidate(t *testing.T) {
+	cases := []struct {
+		attachment slack.Attachment
+		valid      bool
+	}{
+		{slack.Attachment{Color: slack.ColorGood, Text: "ok"}, true},
+		{slack.Attachment{Color: "#439FE0", Text: "ok"}, true},
+		{slack.Attachment{Color: "blue", Text: "ok"}, false},
+		{slack.Attachment{Color: "439FE0", Text: "ok"}, false},
+		{slack.Attachment{Color: slack.ColorGood}, false},
+		{slack.Attachment{TitleLink: "https://example.com", Text: "ok"}, false},
+		{slack.Attachment{Fields: []slack.AttachmentField{{}}}, false},
+	}
+	for _, c := range cases {
+		err := c.attachment.Validate()
+		if c.valid && err != nil {
+			t.Errorf("%+v: unexpected error, %s", c.attachment, err)
+		} else if !c.valid && err == nil {
+			t.Errorf("%+v: no error raised", c.attachment)
+		}
+	}
+}
@@ -0,0 +1,940 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"unicode/utf8"
+)
+
+// Limits of Block Kit checked before posting.
+// See https://api.slack.com/reference/block-kit/blocks
+const (
+	MaxBlocks = 50 // blocks in a message
+
+	maxBlockIDLength     = 255
+	maxSectionTextLength = 3000
+	maxSectionFields     = 10
+	maxFieldTextLength   = 2000
+	maxHeaderTextLength  = 150
+	maxContextElements   = 10
+	maxActionsElements   = 25
+	maxImageURLLength    = 3000
+	maxAltTextLength     = 2000
+	maxImageTitleLength  = 2000
+	maxButtonTextLength  = 75
+	maxActionIDLength    = 255
+	maxButtonValueLength = 2000
+	maxButtonURLLength   = 3000
+	maxRichTextLength    = 4000
+)
+
+// Block type names
+const (
+	SectionBlockType  = "section"
+	HeaderBlockType   = "header"
+	DividerBlockType  = "divider"
+	ContextBlockType  = "context"
+	ImageBlockType    = "image"
+	ActionsBlockType  = "actions"
+	RichTextBlockType = "rich_text"
+)
+
+// Text object types
+const (
+	PlainTextType = "plain_text"
+	MarkdownType  = "mrkdwn"
+)
+
+// Block is a layout block of a message
+type Block interface {
+	// BlockType returns the type name such as "section"
+	BlockType() string
+	// Validate checks the block against limits of Slack
+	Validate() error
+}
+
+// Element is an element placed in section, context, and actions blocks
+type Element interface {
+	// ElementType returns the type name such as "button"
+	ElementType() string
+	// Validate checks the element against limits of Slack
+	Validate() error
+}
+
+// Blocks is a list of blocks which makes up a message
+type Blocks []Block
+
+// Validate checks the number of blocks and each of them.
+func (bs Blocks) Validate() error {
+	if len(bs) == 0 {
+		return errors.New("no block")
+	}
+	if len(bs) > MaxBlocks {
+		return fmt.Errorf("%d blocks exceed the limit of %d", len(bs), MaxBlocks)
+	}
+	for i, b := range bs {
+		if b == nil {
+			return fmt.Errorf("block %d: nil block", i)
+		}
+		if err := b.Validate(); err != nil {
+			return fmt.Errorf("block %d (%s): %s", i, b.BlockType(), err)
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes each block according to its type.
+func (bs *Blocks) UnmarshalJSON(data []byte) error {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return err
+	}
+	blocks := make(Blocks, 0, len(raws))
+	for i, raw := range raws {
+		b, err := unmarshalBlock(raw)
+		if err != nil {
+			return fmt.Errorf("block %d: %s", i, err)
+		}
+		blocks = append(blocks, b)
+	}
+	*bs = blocks
+	return nil
+}
+
+// ParseBlocks decodes blocks from JSON and validates them.
+// Both a list of blocks and an object with "blocks" key, which Block Kit Builder produces, are accepted.
+func ParseBlocks(data []byte) (Blocks, error) {
+	var blocks Blocks
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var payload struct {
+			Blocks Blocks `json:"blocks"`
+		}
+		if err := json.Unmarshal(trimmed, &payload); err != nil {
+			return nil, err
+		}
+		blocks = payload.Blocks
+	} else if err := json.Unmarshal(data, &blocks); err != nil {
+		return nil, err
+	}
+	if err := blocks.Validate(); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// WithBlocks returns an option which lays out a message with blocks.
+// The text of the message is then used as a fallback in notifications.
+func WithBlocks(blocks Blocks) MessageOption {
+	return func(v url.Values) error {
+		if err := blocks.Validate(); err != nil {
+			return err
+		}
+		data, err := json.Marshal(blocks)
+		if err != nil {
+			return err
+		}
+		v.Set("blocks", string(data))
+		return nil
+	}
+}
+
+// SendBlocks sends a message laid out with blocks to a designated channel, and returns the timestamp of the posted message.
+// fallbackText is shown in notifications and clients which cannot render blocks.
+// Blocks are validated before posting.
+// chat:write:user scope should be granted
+// See https://api.slack.com/methods/chat.postMessage
+func (c *Client) SendBlocks(channelID, fallbackText string, blocks Blocks, opts ...MessageOption) (string, error) {
+	return c.SendBlocksContext(context.Background(), channelID, fallbackText, blocks, opts...)
+}
+
+// SendBlocksContext is SendBlocks with a context to cancel the request.
+func (c *Client) SendBlocksContext(ctx context.Context, channelID, fallbackText string, blocks Blocks, opts ...MessageOption) (string, error) {
+	opts = append([]MessageOption{WithBlocks(blocks)}, opts...)
+	return c.SendMessageContext(ctx, channelID, fallbackText, opts...)
+}
+
+// BlockBuilder builds blocks of a message with chained calls, such as
+//
+//	blocks := slack.NewBlockBuilder().
+//		Header("Release v1.2.0").
+//		Section(slack.Markdown("*Changes*\n• faster uploads")).
+//		Divider().
+//		Context(slack.Markdown("released by matthewlujp")).
+//		Build()
+type BlockBuilder struct {
+	blocks Blocks
+}
+
+// NewBlockBuilder returns an empty builder.
+func NewBlockBuilder() *BlockBuilder {
+	return &BlockBuilder{}
+}
+
+// Add appends any block.
+func (b *BlockBuilder) Add(block Block) *BlockBuilder {
+	b.blocks = append(b.blocks, block)
+	return b
+}
+
+// Section appends a section block with text and optional fields shown in two columns.
+func (b *BlockBuilder) Section(text *TextObject, fields ...*TextObject) *BlockBuilder {
+	return b.Add(&SectionBlock{Text: text, Fields: fields})
+}
+
+// Fields appends a section block made of fields only.
+func (b *BlockBuilder) Fields(fields ...*TextObject) *BlockBuilder {
+	return b.Add(&SectionBlock{Fields: fields})
+}
+
+// Header appends a header block in large bold text.
+func (b *BlockBuilder) Header(text string) *BlockBuilder {
+	return b.Add(&HeaderBlock{Text: PlainText(text)})
+}
+
+// Divider appends a divider block.
+func (b *BlockBuilder) Divider() *BlockBuilder {
+	return b.Add(&DividerBlock{})
+}
+
+// Context appends a context block made of text objects and images.
+func (b *BlockBuilder) Context(elements ...Element) *BlockBuilder {
+	return b.Add(&ContextBlock{Elements: elements})
+}
+
+// Image appends an image block.
+func (b *BlockBuilder) Image(imageURL, altText string) *BlockBuilder {
+	return b.Add(&ImageBlock{ImageURL: imageURL, AltText: altText})
+}
+
+// Actions appends an actions block made of interactive elements such as buttons.
+func (b *BlockBuilder) Actions(elements ...Element) *BlockBuilder {
+	return b.Add(&ActionsBlock{Elements: elements})
+}
+
+// RichText appends a rich text block.
+func (b *BlockBuilder) RichText(elements ...*RichTextElement) *BlockBuilder {
+	return b.Add(&RichTextBlock{Elements: elements})
+}
+
+// Build returns the blocks appended so far.
+func (b *BlockBuilder) Build() Blocks {
+	return b.blocks
+}
+
+// TextObject is text in either plain_text or mrkdwn format
+type TextObject struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Emoji    bool   `json:"emoji,omitempty"`    // plain_text only
+	Verbatim bool   `json:"verbatim,omitempty"` // mrkdwn only
+}
+
+// PlainText returns a plain_text object in which emoji are rendered.
+func PlainText(text string) *TextObject {
+	return &TextObject{Type: PlainTextType, Text: text, Emoji: true}
+}
+
+// Markdown returns a mrkdwn object.
+func Markdown(text string) *TextObject {
+	return &TextObject{Type: MarkdownType, Text: text}
+}
+
+// ElementType returns the type of the text, which lets it be placed in context blocks.
+func (t *TextObject) ElementType() string { return t.Type }
+
+// Validate checks the type and that the text is not empty.
+func (t *TextObject) Validate() error {
+	if t.Type != PlainTextType && t.Type != MarkdownType {
+		return fmt.Errorf("unknown text type %q", t.Type)
+	}
+	if t.Text == "" {
+		return errors.New("empty text")
+	}
+	return nil
+}
+
+// validateText checks a text object against a length limit.
+func validateText(name string, t *TextObject, limit int) error {
+	if t == nil {
+		return fmt.Errorf("%s is missing", name)
+	}
+	if err := t.Validate(); err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	return checkLength(name, t.Text, limit)
+}
+
+// checkLength checks that s has at most limit characters.
+func checkLength(name, s string, limit int) error {
+	if n := utf8.RuneCountInString(s); n > limit {
+		return fmt.Errorf("%s has %d characters, which exceeds the limit of %d", name, n, limit)
+	}
+	return nil
+}
+
+// SectionBlock shows text, fields, and an optional accessory element
+// See https://api.slack.com/reference/block-kit/blocks#section
+type SectionBlock struct {
+	BlockID   string        `json:"block_id,omitempty"`
+	Text      *TextObject   `json:"text,omitempty"`
+	Fields    []*TextObject `json:"fields,omitempty"`
+	Accessory Element       `json:"accessory,omitempty"`
+}
+
+// BlockType returns "section".
+func (s *SectionBlock) BlockType() string { return SectionBlockType }
+
+// Validate checks the text, fields, and accessory.
+func (s *SectionBlock) Validate() error {
+	if err := checkLength("block_id", s.BlockID, maxBlockIDLength); err != nil {
+		return err
+	}
+	if s.Text == nil && len(s.Fields) == 0 {
+		return errors.New("either text or fields is required")
+	}
+	if s.Text != nil {
+		if err := validateText("text", s.Text, maxSectionTextLength); err != nil {
+			return err
+		}
+	}
+	if len(s.Fields) > maxSectionFields {
+		return fmt.Errorf("%d fields exceed the limit of %d", len(s.Fields), maxSectionFields)
+	}
+	for i, f := range s.Fields {
+		if err := validateText(fmt.Sprintf("field %d", i), f, maxFieldTextLength); err != nil {
+			return err
+		}
+	}
+	if s.Accessory != nil {
+		if err := s.Accessory.Validate(); err != nil {
+			return fmt.Errorf("accessory: %s", err)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON adds the block type.
+func (s *SectionBlock) MarshalJSON() ([]byte, error) {
+	type alias SectionBlock
+	return marshalWithType(SectionBlockType, (*alias)(s))
+}
+
+// UnmarshalJSON decodes the accessory according to its type.
+func (s *SectionBlock) UnmarshalJSON(data []byte) error {
+	type alias SectionBlock
+	aux := &struct {
+		*alias
+		Accessory json.RawMessage `json:"accessory"`
+	}{alias: (*alias)(s)}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	if len(aux.Accessory) == 0 {
+		s.Accessory = nil
+		return nil
+	}
+	e, err := unmarshalElement(aux.Accessory)
+	if err != nil {
+		return fmt.Errorf("accessory: %s", err)
+	}
+	s.Accessory = e
+	return nil
+}
+
+// HeaderBlock shows plain text in a larger, bold font
+// See https://api.slack.com/reference/block-kit/blocks#header
+type HeaderBlock struct {
+	BlockID string      `json:"block_id,omitempty"`
+	Text    *TextObject `json:"text"`
+}
+
+// BlockType returns "header".
+func (h *HeaderBlock) BlockType() string { return HeaderBlockType }
+
+// Validate checks that the text is plain_text within the limit.
+func (h *HeaderBlock) Validate() error {
+	if err := checkLength("block_id", h.BlockID, maxBlockIDLength); err != nil {
+		return err
+	}
+	if err := validateText("text", h.Text, maxHeaderTextLength); err != nil {
+		return err
+	}
+	if h.Text.Type != PlainTextType {
+		return fmt.Errorf("text should be %s", PlainTextType)
+	}
+	return nil
+}
+
+// MarshalJSON adds the block type.
+func (h *HeaderBlock) MarshalJSON() ([]byte, error) {
+	type alias HeaderBlock
+	return marshalWithType(HeaderBlockType, (*alias)(h))
+}
+
+// DividerBlock draws a horizontal line
+// See https://api.slack.com/reference/block-kit/blocks#divider
+type DividerBlock struct {
+	BlockID string `json:"block_id,omitempty"`
+}
+
+// BlockType returns "divider".
+func (d *DividerBlock) BlockType() string { return DividerBlockType }
+
+// Validate checks the block id.
+func (d *DividerBlock) Validate() error {
+	return checkLength("block_id", d.BlockID, maxBlockIDLength)
+}
+
+// MarshalJSON adds the block type.
+func (d *DividerBlock) MarshalJSON() ([]byte, error) {
+	type alias DividerBlock
+	return marshalWithType(DividerBlockType, (*alias)(d))
+}
+
+// ContextBlock shows small text and images
+// See https://api.slack.com/reference/block-kit/blocks#context
+type ContextBlock struct {
+	BlockID  string    `json:"block_id,omitempty"`
+	Elements []Element `json:"elements"`
+}
+
+// BlockType returns "context".
+func (c *ContextBlock) BlockType() string { return ContextBlockType }
+
+// Validate checks that elements are text objects or images within the limit.
+func (c *ContextBlock) Validate() error {
+	if err := checkLength("block_id", c.BlockID, maxBlockIDLength); err != nil {
+		return err
+	}
+	if len(c.Elements) == 0 {
+		return errors.New("no element")
+	}
+	if len(c.Elements) > maxContextElements {
+		return fmt.Errorf("%d elements exceed the limit of %d", len(c.Elements), maxContextElements)
+	}
+	for i, e := range c.Elements {
+		switch e.(type) {
+		case *TextObject, *ImageElement:
+		case nil:
+			return fmt.Errorf("element %d: nil element", i)
+		default:
+			return fmt.Errorf("element %d: %s is not allowed in context", i, e.ElementType())
+		}
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("element %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON adds the block type.
+func (c *ContextBlock) MarshalJSON() ([]byte, error) {
+	type alias ContextBlock
+	return marshalWithType(ContextBlockType, (*alias)(c))
+}
+
+// UnmarshalJSON decodes elements according to their types.
+func (c *ContextBlock) UnmarshalJSON(data []byte) error {
+	type alias ContextBlock
+	aux := &struct {
+		*alias
+		Elements []json.RawMessage `json:"elements"`
+	}{alias: (*alias)(c)}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	elements, err := unmarshalElements(aux.Elements)
+	if err != nil {
+		return err
+	}
+	c.Elements = elements
+	return nil
+}
+
+// ImageBlock shows an image
+// See https://api.slack.com/reference/block-kit/blocks#image
+type ImageBlock struct {
+	BlockID  string      `json:"block_id,omitempty"`
+	ImageURL string      `json:"image_url"`
+	AltText  string      `json:"alt_text"`
+	Title    *TextObject `json:"title,omitempty"`
+}
+
+// BlockType returns "image".
+func (i *ImageBlock) BlockType() string { return ImageBlockType }
+
+// Validate checks the url, alternative text, and title.
+func (i *ImageBlock) Validate() error {
+	if err := checkLength("block_id", i.BlockID, maxBlockIDLength); err != nil {
+		return err
+	}
+	if err := validateImage(i.ImageURL, i.AltText); err != nil {
+		return err
+	}
+	if i.Title != nil {
+		if err := validateText("title", i.Title, maxImageTitleLength); err != nil {
+			return err
+		}
+		if i.Title.Type != PlainTextType {
+			return fmt.Errorf("title should be %s", PlainTextType)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON adds the block type.
+func (i *ImageBlock) MarshalJSON() ([]byte, error) {
+	type alias ImageBlock
+	return marshalWithType(ImageBlockType, (*alias)(i))
+}
+
+// validateImage checks the url and alternative text of an image.
+func validateImage(imageURL, altText string) error {
+	if imageURL == "" {
+		return errors.New("image_url is missing")
+	}
+	if err := checkLength("image_url", imageURL, maxImageURLLength); err != nil {
+		return err
+	}
+	if altText == "" {
+		return errors.New("alt_text is missing")
+	}
+	return checkLength("alt_text", altText, maxAltTextLength)
+}
+
+// ActionsBlock holds interactive elements
+// See https://api.slack.com/reference/block-kit/blocks#actions
+type ActionsBlock struct {
+	BlockID  string    `json:"block_id,omitempty"`
+	Elements []Element `json:"elements"`
+}
+
+// BlockType returns "actions".
+func (a *ActionsBlock) BlockType() string { return ActionsBlockType }
+
+// Validate checks that elements are interactive within the limit.
+func (a *ActionsBlock) Validate() error {
+	if err := checkLength("block_id", a.BlockID, maxBlockIDLength); err != nil {
+		return err
+	}
+	if len(a.Elements) == 0 {
+		return errors.New("no element")
+	}
+	if len(a.Elements) > maxActionsElements {
+		return fmt.Errorf("%d elements exceed the limit of %d", len(a.Elements), maxActionsElements)
+	}
+	for i, e := range a.Elements {
+		if e == nil {
+			return fmt.Errorf("element %d: nil element", i)
+		}
+		if _, ok := e.(*ButtonElement); !ok {
+			return fmt.Errorf("element %d: %s is not allowed in actions", i, e.ElementType())
+		}
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("element %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON adds the block type.
+func (a *ActionsBlock) MarshalJSON() ([]byte, error) {
+	type alias ActionsBlock
+	return marshalWithType(ActionsBlockType, (*alias)(a))
+}
+
+// UnmarshalJSON decodes elements according to their types.
+func (a *ActionsBlock) UnmarshalJSON(data []byte) error {
+	type alias ActionsBlock
+	aux := &struct {
+		*alias
+		Elements []json.RawMessage `json:"elements"`
+	}{alias: (*alias)(a)}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	elements, err := unmarshalElements(aux.Elements)
+	if err != nil {
+		return err
+	}
+	a.Elements = elements
+	return nil
+}
+
+// RichTextBlock shows formatted text made of sections, lists, quotes, and preformatted text
+// See https://api.slack.com/reference/block-kit/blocks#rich_text
+type RichTextBlock struct {
+	BlockID  string             `json:"block_id,omitempty"`
+	Elements []*RichTextElement `json:"elements"`
+}
+
+// BlockType returns "rich_text".
+func (r *RichTextBlock) BlockType() string { return RichTextBlockType }
+
+// Validate checks types of elements.
+func (r *RichTextBlock) Validate() error {
+	if err := checkLength("block_id", r.BlockID, maxBlockIDLength); err != nil {
+		return err
+	}
+	if len(r.Elements) == 0 {
+		return errors.New("no element")
+	}
+	for i, e := range r.Elements {
+		if err := e.validate(); err != nil {
+			return fmt.Errorf("element %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON adds the block type.
+func (r *RichTextBlock) MarshalJSON() ([]byte, error) {
+	type alias RichTextBlock
+	return marshalWithType(RichTextBlockType, (*alias)(r))
+}
+
+// RichTextElement is one of rich_text_section, rich_text_list, rich_text_quote, and rich_text_preformatted.
+// A list holds sections as Sections, while the others hold pieces of text as Elements.
+type RichTextElement struct {
+	Type     string             `json:"type"`
+	Elements []*RichTextItem    `json:"-"`
+	Sections []*RichTextElement `json:"-"`
+	Style    string             `json:"style,omitempty"`  // "bullet" or "ordered" for lists
+	Indent   int                `json:"indent,omitempty"` // lists only
+}
+
+// RichTextSection returns a paragraph made of items.
+func RichTextSection(items ...*RichTextItem) *RichTextElement {
+	return &RichTextElement{Type: "rich_text_section", Elements: items}
+}
+
+// RichTextList returns a list whose style is "bullet" or "ordered".
+func RichTextList(style string, sections ...*RichTextElement) *RichTextElement {
+	return &RichTextElement{Type: "rich_text_list", Style: style, Sections: sections}
+}
+
+// RichTextQuote returns a quote made of items.
+func RichTextQuote(items ...*RichTextItem) *RichTextElement {
+	return &RichTextElement{Type: "rich_text_quote", Elements: items}
+}
+
+// RichTextPreformatted returns a code block made of items.
+func RichTextPreformatted(items ...*RichTextItem) *RichTextElement {
+	return &RichTextElement{Type: "rich_text_preformatted", Elements: items}
+}
+
+func (e *RichTextElement) validate() error {
+	if e == nil {
+		return errors.New("nil element")
+	}
+	switch e.Type {
+	case "rich_text_list":
+		if e.Style != "bullet" && e.Style != "ordered" {
+			return fmt.Errorf("unknown list style %q", e.Style)
+		}
+		for i, s := range e.Sections {
+			if s == nil || s.Type != "rich_text_section" {
+				return fmt.Errorf("list item %d should be rich_text_section", i)
+			}
+			if err := s.validate(); err != nil {
+				return fmt.Errorf("list item %d: %s", i, err)
+			}
+		}
+	case "rich_text_section", "rich_text_quote", "rich_text_preformatted":
+		for i, item := range e.Elements {
+			if err := item.validate(); err != nil {
+				return fmt.Errorf("item %d: %s", i, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown rich text element type %q", e.Type)
+	}
+	return nil
+}
+
+// MarshalJSON puts either sections or items in "elements".
+func (e *RichTextElement) MarshalJSON() ([]byte, error) {
+	type alias RichTextElement
+	aux := &struct {
+		*alias
+		Elements interface{} `json:"elements"`
+	}{alias: (*alias)(e), Elements: e.Elements}
+	if e.Type == "rich_text_list" {
+		aux.Elements = e.Sections
+	}
+	if aux.Elements == nil {
+		aux.Elements = []struct{}{}
+	}
+	return json.Marshal(aux)
+}
+
+// UnmarshalJSON reads "elements" as sections of a list or items of the others.
+func (e *RichTextElement) UnmarshalJSON(data []byte) error {
+	type alias RichTextElement
+	aux := &struct {
+		*alias
+		Elements json.RawMessage `json:"elements"`
+	}{alias: (*alias)(e)}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	if len(aux.Elements) == 0 {
+		return nil
+	}
+	if e.Type == "rich_text_list" {
+		return json.Unmarshal(aux.Elements, &e.Sections)
+	}
+	return json.Unmarshal(aux.Elements, &e.Elements)
+}
+
+// RichTextItem is a piece of rich text, which is one of text, link, emoji, user, and channel
+type RichTextItem struct {
+	Type      string         `json:"type"`
+	Text      string         `json:"text,omitempty"`       // text and link
+	URL       string         `json:"url,omitempty"`        // link
+	Name      string         `json:"name,omitempty"`       // emoji
+	UserID    string         `json:"user_id,omitempty"`    // user
+	ChannelID string         `json:"channel_id,omitempty"` // channel
+	Style     *RichTextStyle `json:"style,omitempty"`
+}
+
+// RichTextStyle decorates a piece of rich text
+type RichTextStyle struct {
+	Bold   bool `json:"bold,omitempty"`
+	Italic bool `json:"italic,omitempty"`
+	Strike bool `json:"strike,omitempty"`
+	Code   bool `json:"code,omitempty"`
+}
+
+// RichText returns a piece of text with an optional style.
+func RichText(text string, style *RichTextStyle) *RichTextItem {
+	return &RichTextItem{Type: "text", Text: text, Style: style}
+}
+
+// RichTextLink returns a link, whose text defaults to the url when empty.
+func RichTextLink(link, text string) *RichTextItem {
+	return &RichTextItem{Type: "link", URL: link, Text: text}
+}
+
+func (item *RichTextItem) validate() error {
+	if item == nil {
+		return errors.New("nil item")
+	}
+	var missing string
+	switch item.Type {
+	case "text":
+		if item.Text == "" {
+			missing = "text"
+		}
+	case "link":
+		if item.URL == "" {
+			missing = "url"
+		}
+	case "emoji":
+		if item.Name == "" {
+			missing = "name"
+		}
+	case "user":
+		if item.UserID == "" {
+			missing = "user_id"
+		}
+	case "channel":
+		if item.ChannelID == "" {
+			missing = "channel_id"
+		}
+	default:
+		return fmt.Errorf("unknown rich text item type %q", item.Type)
+	}
+	if missing != "" {
+		return fmt.Errorf("%s of %s is missing", missing, item.Type)
+	}
+	return checkLength(item.Type, item.Text, maxRichTextLength)
+}
+
+// ImageElement is a small image in context blocks and section accessories
+// See https://api.slack.com/reference/block-kit/block-elements#image
+type ImageElement struct {
+	ImageURL string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+}
+
+// ElementType returns "image".
+func (i *ImageElement) ElementType() string { return "image" }
+
+// Validate checks the url and alternative text.
+func (i *ImageElement) Validate() error {
+	return validateImage(i.ImageURL, i.AltText)
+}
+
+// MarshalJSON adds the element type.
+func (i *ImageElement) MarshalJSON() ([]byte, error) {
+	type alias ImageElement
+	return marshalWithType("image", (*alias)(i))
+}
+
+// ButtonElement is a button in actions blocks and section accessories.
+// A button with URL opens it in a browser.
+// See https://api.slack.com/reference/block-kit/block-elements#button
+type ButtonElement struct {
+	Text     *TextObject `json:"text"`
+	ActionID string      `json:"action_id,omitempty"`
+	URL      string      `json:"url,omitempty"`
+	Value    string      `json:"value,omitempty"`
+	Style    string      `json:"style,omitempty"` // "primary" or "danger"
+}
+
+// Button returns a button labeled text.
+func Button(text, actionID string) *ButtonElement {
+	return &ButtonElement{Text: PlainText(text), ActionID: actionID}
+}
+
+// WithURL sets a url opened when the button is clicked.
+func (b *ButtonElement) WithURL(link string) *ButtonElement {
+	b.URL = link
+	return b
+}
+
+// WithValue sets a value sent along with interaction payloads.
+func (b *ButtonElement) WithValue(value string) *ButtonElement {
+	b.Value = value
+	return b
+}
+
+// WithStyle sets "primary" or "danger" style.
+func (b *ButtonElement) WithStyle(style string) *ButtonElement {
+	b.Style = style
+	return b
+}
+
+// ElementType returns "button".
+func (b *ButtonElement) ElementType() string { return "button" }
+
+// Validate checks the label, ids, and style.
+func (b *ButtonElement) Validate() error {
+	if err := validateText("text", b.Text, maxButtonTextLength); err != nil {
+		return err
+	}
+	if b.Text.Type != PlainTextType {
+		return fmt.Errorf("text should be %s", PlainTextType)
+	}
+	if err := checkLength("action_id", b.ActionID, maxActionIDLength); err != nil {
+		return err
+	}
+	if err := checkLength("url", b.URL, maxButtonURLLength); err != nil {
+		return err
+	}
+	if err := checkLength("value", b.Value, maxButtonValueLength); err != nil {
+		return err
+	}
+	if b.Style != "" && b.Style != "primary" && b.Style != "danger" {
+		return fmt.Errorf("unknown button style %q", b.Style)
+	}
+	return nil
+}
+
+// MarshalJSON adds the element type.
+func (b *ButtonElement) MarshalJSON() ([]byte, error) {
+	type alias ButtonElement
+	return marshalWithType("button", (*alias)(b))
+}
+
+// marshalWithType encodes v, which should be a struct, with "type" key prepended.
+func marshalWithType(typ string, v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	head, err := json.Marshal(map[string]string{"type": typ})
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "{}" {
+		return head, nil
+	}
+	// join {"type":"..."} and {...} into one object
+	return append(append(head[:len(head)-1], ','), data[1:]...), nil
+}
+
+// peekType reads "type" key of a json object.
+func peekType(raw json.RawMessage) (string, error) {
+	var t struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return "", err
+	}
+	if t.Type == "" {
+		return "", errors.New("type is missing")
+	}
+	return t.Type, nil
+}
+
+// unmarshalBlock decodes a block into the type named by its "type" key.
+func unmarshalBlock(raw json.RawMessage) (Block, error) {
+	typ, err := peekType(raw)
+	if err != nil {
+		return nil, err
+	}
+	var b Block
+	switch typ {
+	case SectionBlockType:
+		b = &SectionBlock{}
+	case HeaderBlockType:
+		b = &HeaderBlock{}
+	case DividerBlockType:
+		b = &DividerBlock{}
+	case ContextBlockType:
+		b = &ContextBlock{}
+	case ImageBlockType:
+		b = &ImageBlock{}
+	case ActionsBlockType:
+		b = &ActionsBlock{}
+	case RichTextBlockType:
+		b = &RichTextBlock{}
+	default:
+		return nil, fmt.Errorf("unknown block type %q", typ)
+	}
+	if err := json.Unmarshal(raw, b); err != nil {
+		return nil, fmt.Errorf("%s: %s", typ, err)
+	}
+	return b, nil
+}
+
+// unmarshalElement decodes an element into the type named by its "type" key.
+func unmarshalElement(raw json.RawMessage) (Element, error) {
+	typ, err := peekType(raw)
+	if err != nil {
+		return nil, err
+	}
+	var e Element
+	switch typ {
+	case PlainTextType, MarkdownType:
+		e = &TextObject{}
+	case "image":
+		e = &ImageElement{}
+	case "button":
+		e = &ButtonElement{}
+	default:
+		return nil, fmt.Errorf("unknown element type %q", typ)
+	}
+	if err := json.Unmarshal(raw, e); err != nil {
+		return nil, fmt.Errorf("%s: %s", typ, err)
+	}
+	return e, nil
+}
+
+func unmarshalElements(raws []json.RawMessage) ([]Element, error) {
+	elements := make([]Element, 0, len(raws))
+	for i, raw := range raws {
+		e, err := unmarshalElement(raw)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %s", i, err)
+		}
+		elements = append(elements, e)
+	}
+	return elements, nil
+}
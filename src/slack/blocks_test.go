@@ -0,0 +1,98 @@
+package slack_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+func TestBlockBuilder(t *testing.T) {
+	blocks := slack.NewBlockBuilder().
+		Header("Release v1.2.0").
+		Section(slack.Markdown("*Changes*"), slack.Markdown("a"), slack.Markdown("b")).
+		Divider().
+		Context(slack.Markdown("by hoge"), &slack.ImageElement{ImageURL: "https://example.com/a.png", AltText: "a"}).
+		Actions(slack.Button("Open", "open").WithURL("https://example.com").WithStyle("primary")).
+		RichText(slack.RichTextList("bullet", slack.RichTextSection(slack.RichText("foo", &slack.RichTextStyle{Bold: true})))).
+		Build()
+	if err := blocks.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"type":"header","text":{"type":"plain_text","text":"Release v1.2.0","emoji":true}},` +
+		`{"type":"section","text":{"type":"mrkdwn","text":"*Changes*"},"fields":[{"type":"mrkdwn","text":"a"},{"type":"mrkdwn","text":"b"}]},` +
+		`{"type":"divider"},` +
+		`{"type":"context","elements":[{"type":"mrkdwn","text":"by hoge"},{"type":"image","image_url":"https://example.com/a.png","alt_text":"a"}]},` +
+		`{"type":"actions","elements":[{"type":"button","text":{"type":"plain_text","text":"Open","emoji":true},"action_id":"open","url":"https://example.com","style":"primary"}]},` +
+		`{"type":"rich_text","elements":[{"type":"rich_text_list","style":"bullet","elements":[{"type":"rich_text_section","elements":[{"type":"text","text":"foo","style":{"bold":true}}]}]}]}]`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	// decoding gives the same blocks back
+	parsed, err := slack.ParseBlocks(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reencoded, _ := json.Marshal(parsed)
+	if string(reencoded) != expected {
+		t.Errorf("round trip mismatch, got %s", reencoded)
+	}
+}
+
+func TestParseBlocks(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		valid bool
+	}{
+		{"list", `[{"type":"section","text":{"type":"mrkdwn","text":"hoge"}}]`, true},
+		{"builder payload", `{"blocks":[{"type":"divider"}]}`, true},
+		{"unknown block type", `[{"type":"sectoin","text":{"type":"mrkdwn","text":"hoge"}}]`, false},
+		{"missing type", `[{"text":{"type":"mrkdwn","text":"hoge"}}]`, false},
+		{"unknown element type", `[{"type":"actions","elements":[{"type":"bottun"}]}]`, false},
+		{"empty", `[]`, false},
+		{"section without text", `[{"type":"section"}]`, false},
+		{"markdown header", `[{"type":"header","text":{"type":"mrkdwn","text":"hoge"}}]`, false},
+		{"long header", `[{"type":"header","text":{"type":"plain_text","text":"` + strings.Repeat("a", 151) + `"}}]`, false},
+		{"long section", `[{"type":"section","text":{"type":"mrkdwn","text":"` + strings.Repeat("あ", 3001) + `"}}]`, false},
+		{"button in context", `[{"type":"context","elements":[{"type":"button","text":{"type":"plain_text","text":"a"}}]}]`, false},
+		{"image without alt text", `[{"type":"image","image_url":"https://example.com/a.png"}]`, false},
+		{"too many blocks", `[` + strings.TrimSuffix(strings.Repeat(`{"type":"divider"},`, slack.MaxBlocks+1), ",") + `]`, false},
+		{"max blocks", `[` + strings.TrimSuffix(strings.Repeat(`{"type":"divider"},`, slack.MaxBlocks), ",") + `]`, true},
+	}
+	for _, c := range cases {
+		_, err := slack.ParseBlocks([]byte(c.input))
+		if c.valid && err != nil {
+			t.Errorf("%s: unexpected error, %s", c.name, err)
+		} else if !c.valid && err == nil {
+			t.Errorf("%s: no error raised", c.name)
+		}
+	}
+}
+
+func TestSendBlocks(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(server.URL))
+	blocks := slack.NewBlockBuilder().Section(slack.Markdown("*hoge*")).Build()
+	ts, err := client.SendBlocks(targetChannel, targetText, blocks)
+	if err != nil {
+		t.Fatalf("sending blocks failed, %s", err)
+	}
+	if ts != targetTS {
+		t.Errorf("expected ts %s, got %s", targetTS, ts)
+	}
+
+	// invalid blocks are not sent
+	if _, err := client.SendBlocks(targetChannel, targetText, slack.NewBlockBuilder().Header("").Build()); err == nil {
+		t.Error("no error raised on invalid blocks")
+	}
+}
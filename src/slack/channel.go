@@ -6,6 +6,8 @@ type Channel struct {
 	Name            string   `json:"name"`
 	Members         []string `json:"members"`
 	IsMember        bool     `json:"is_member"`
+	IsPrivate       bool     `json:"is_private"`
+	NumMembers      int      `json:"num_members"`
 	Purpose         Purpose  `json:"purpose"`
 	IsDirectMessage bool     `json:"is_im"`
 	User            string   `json:"user"`
@@ -15,3 +17,14 @@ type Channel struct {
 type Purpose struct {
 	Value string `json:"value"`
 }
+
+// MemberCount returns the number of members, which is 2 for direct messages.
+func (c *Channel) MemberCount() int {
+	switch {
+	case c.NumMembers > 0:
+		return c.NumMembers
+	case c.IsDirectMessage:
+		return 2
+	}
+	return len(c.Members)
+}
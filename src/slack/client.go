@@ -2,17 +2,19 @@ package slack
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
-	"mime/multipart"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
-	"os"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // Client is a wrapper for Slack web api
@@ -21,6 +23,15 @@ type Client struct {
 	httpClient *http.Client
 	baseURL    string
 	logger     *log.Logger
+	pageLimit  int
+
+	uploadMethod UploadMethod
+
+	// retry and throttling
+	maxRetries  int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+	limiter     *rateLimiter
 }
 
 // NewClient returns a client object to call Slack web api.
@@ -39,6 +50,12 @@ func NewClient(token string, logger *log.Logger, opts ...Option) (*Client, error
 		httpClient: http.DefaultClient,
 		baseURL:    SlackAPIBaseURL,
 		logger:     logger,
+		pageLimit:  DefaultPageLimit,
+
+		maxRetries:  DefaultMaxRetries,
+		backoffBase: DefaultBackoffBase,
+		backoffMax:  DefaultBackoffMax,
+		limiter:     newRateLimiter(),
 	}
 
 	// parse options
@@ -56,8 +73,8 @@ func (c *Client) buildURL(endpoint string) string {
 	return fmt.Sprintf("%s/%s", c.baseURL, endpoint)
 }
 
-func (c *Client) buildRequest(requestMethod, targetOp, token string, body io.Reader) (*http.Request, error) {
-	req, err := http.NewRequest(requestMethod, c.buildURL(targetOp), body)
+func (c *Client) buildRequest(ctx context.Context, requestMethod, targetOp, token string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, requestMethod, c.buildURL(targetOp), body)
 	if err != nil {
 		return nil, err
 	}
@@ -66,42 +83,113 @@ func (c *Client) buildRequest(requestMethod, targetOp, token string, body io.Rea
 	return req, nil
 }
 
-func (c *Client) get(method string) (*http.Response, error) {
-	req, err := c.buildRequest("GET", method, c.token, nil)
-	if err != nil {
-		return nil, err
-	}
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("response status %s", res.Status)
-	}
-	return res, nil
+func (c *Client) get(ctx context.Context, method string, params url.Values) (*http.Response, error) {
+	targetOp := method
+	if len(params) > 0 {
+		targetOp = fmt.Sprintf("%s?%s", method, params.Encode())
+	}
+	return c.do(ctx, method, func() (*http.Request, error) {
+		return c.buildRequest(ctx, "GET", targetOp, c.token, nil)
+	})
 }
 
-func (c *Client) post(method string, body io.Reader) (*http.Response, error) {
-	req, err := c.buildRequest("POST", method, c.token, body)
+func (c *Client) post(ctx context.Context, method string, body io.Reader) (*http.Response, error) {
+	// keep the payload so that the request can be sent again on retry
+	payload, err := ioutil.ReadAll(body)
 	if err != nil {
 		return nil, err
 	}
+	return c.do(ctx, method, func() (*http.Request, error) {
+		return c.buildRequest(ctx, "POST", method, c.token, bytes.NewReader(payload))
+	})
+}
 
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("response status %s", res.Status)
+// do sends a request built by newRequest.
+// It waits for the budget of the method, and retries on 429 Too Many Requests honoring Retry-After,
+// and on transient network errors with exponential backoff.
+// Since Slack may have handled a request which failed with 5xx status or with an error after it was sent,
+// such a request is retried only when it is idempotent, so that a message is never posted twice.
+// Waiting is interrupted when ctx is done.
+func (c *Client) do(ctx context.Context, method string, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	var delay time.Duration
+	for attempt := 0; ; attempt++ {
+		if c.limiter != nil {
+			if d := c.limiter.reserve(method); d > delay {
+				delay = d
+			}
+		}
+		if delay > 0 {
+			c.logger.Printf("[do] waiting %s before calling %s", delay, method)
+			if err := sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		idempotent := isIdempotent(req.Method, method)
+		var sent int32
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			WroteRequest: func(info httptrace.WroteRequestInfo) {
+				if info.Err == nil {
+					atomic.StoreInt32(&sent, 1)
+				}
+			},
+		}))
+		res, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || !isTransient(err) || attempt >= c.maxRetries {
+				return nil, err
+			}
+			if !idempotent && atomic.LoadInt32(&sent) == 1 {
+				// Slack may have received the request even though the response was lost
+				return nil, err
+			}
+			c.logger.Printf("[do] %s failed, %s", method, err)
+			delay = c.backoff(attempt)
+			continue
+		}
+
+		switch {
+		case res.StatusCode == http.StatusOK:
+			return res, nil
+		case res.StatusCode == http.StatusTooManyRequests:
+			res.Body.Close()
+			retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After"))
+			if !ok {
+				retryAfter = c.backoff(attempt)
+			}
+			if c.limiter != nil {
+				c.limiter.penalize(method, retryAfter)
+			}
+			delay = retryAfter
+		case res.StatusCode >= http.StatusInternalServerError && idempotent:
+			res.Body.Close()
+			delay = c.backoff(attempt)
+		default:
+			res.Body.Close()
+			return nil, statusError(method, res)
+		}
+
+		if attempt >= c.maxRetries {
+			return nil, statusError(method, res)
+		}
+		c.logger.Printf("[do] %s responded %s", method, res.Status)
 	}
-	return res, nil
 }
 
-// ObtainWorkspaceInfo gets the current workspace info from Slack api.
+// ObtainWorkspaceInfo gets the current workspace info from Slack api, along with scopes granted to the token.
 // team:read scope should be granted beforehand.
 // See https://api.slack.com/methods/team.info
 func (c *Client) ObtainWorkspaceInfo() (*Workspace, error) {
-	res, err := c.get("team.info")
+	return c.ObtainWorkspaceInfoContext(context.Background())
+}
+
+// ObtainWorkspaceInfoContext is ObtainWorkspaceInfo with a context to cancel the request.
+func (c *Client) ObtainWorkspaceInfoContext(ctx context.Context) (*Workspace, error) {
+	res, err := c.get(ctx, "team.info", nil)
 	if err != nil {
 		c.logger.Printf("[ObtainWorkspaceInfo] request failed, %s", err)
 		return nil, err
@@ -110,20 +198,20 @@ func (c *Client) ObtainWorkspaceInfo() (*Workspace, error) {
 
 	// parse json
 	wInfo := &struct {
-		Ok    bool      `json:"ok"`
-		Error string    `json:"error"`
-		Team  Workspace `json:"team"`
+		apiResponse
+		Team Workspace `json:"team"`
 	}{}
 	if err := json.NewDecoder(res.Body).Decode(wInfo); err != nil {
 		c.logger.Printf("[ObtainWorkspaceInfo] failed in decoding response json, %s", err)
 		return nil, err
 	}
 
-	if !wInfo.Ok {
-		c.logger.Printf("[ObtainWorkspaceInfo] response does not contain workspace info, %s", wInfo.Error)
-		return nil, errors.New(wInfo.Error)
+	if err := wInfo.err("team.info", res); err != nil {
+		c.logger.Printf("[ObtainWorkspaceInfo] response does not contain workspace info, %s", err)
+		return nil, err
 	}
 	wInfo.Team.Token = c.token
+	wInfo.Team.Scopes = parseScopes(res.Header.Get("X-OAuth-Scopes"))
 	return &wInfo.Team, nil
 }
 
@@ -131,28 +219,21 @@ func (c *Client) ObtainWorkspaceInfo() (*Workspace, error) {
 // users:read scope should be granted beforehand.
 // See https://api.slack.com/methods/users.list
 func (c *Client) GetMembers() (Members, error) {
-	res, err := c.get("users.list")
-	if err != nil {
-		c.logger.Printf("[GetMembers] request failed, %s", err)
-		return nil, err
-	}
-	defer res.Body.Close()
+	return c.GetMembersContext(context.Background())
+}
 
-	// parse json response
-	parsed := &struct {
-		Ok      bool    `json:"ok"`
-		Error   string  `json:"error"`
-		Members Members `json:"members"`
-	}{}
-	if err := json.NewDecoder(res.Body).Decode(parsed); err != nil {
-		c.logger.Printf("[GetMembers] parsing json response failed")
+// GetMembersContext is GetMembers with a context to cancel requests.
+func (c *Client) GetMembersContext(ctx context.Context) (Members, error) {
+	var members Members
+	it := c.MemberPagesContext(ctx)
+	for it.Next() {
+		members = append(members, it.Members()...)
+	}
+	if err := it.Err(); err != nil {
+		c.logger.Printf("[GetMembers] request failed, %s", err)
 		return nil, err
 	}
-	if !parsed.Ok {
-		c.logger.Printf("[GetMembers] request rejected by Slack, %s", parsed.Error)
-		return nil, errors.New(parsed.Error)
-	}
-	return parsed.Members, nil
+	return members, nil
 }
 
 // CollectChannels collects channels which a user joins in the current workspace.
@@ -160,12 +241,26 @@ func (c *Client) GetMembers() (Members, error) {
 // channels:read, groups:read, im:read, and mpim:read scopes should be granted.
 // See https://api.slack.com/methods/channels.list, https://api.slack.com/methods/groups.list, https://api.slack.com/methods/conversations.list, and https://api.slack.com/methods/im.list
 func (c *Client) CollectChannels() ([]Channel, error) {
+	return c.CollectChannelsContext(context.Background())
+}
+
+// CollectChannelsContext is CollectChannels with a context to cancel requests.
+func (c *Client) CollectChannelsContext(ctx context.Context) ([]Channel, error) {
+	channels, _, err := c.CollectChannelsAndMembersContext(ctx)
+	return channels, err
+}
+
+// CollectChannelsAndMembersContext is CollectChannelsContext which also returns members of the workspace.
+// Members are obtained to name direct message channels anyway,
+// so callers resolving user names should use them rather than calling GetMembers again, which is rate limited.
+// users:read scope should be granted in addition.
+func (c *Client) CollectChannelsAndMembersContext(ctx context.Context) ([]Channel, Members, error) {
 	collectedChannels := make(map[string]Channel)
 	for _, m := range []string{"channels.list", "conversations.list", "groups.list", "im.list"} {
-		chans, err := c.getChannels(m)
+		chans, err := c.getChannels(ctx, m)
 		if err != nil {
 			c.logger.Printf("[CollectChannels] inquiring channels from %s failed, %s", m, err)
-			return nil, err
+			return nil, nil, err
 		}
 
 		for _, c := range chans {
@@ -178,10 +273,10 @@ func (c *Client) CollectChannels() ([]Channel, error) {
 
 	// edit if direct message
 	var channels []Channel
-	members, err := c.GetMembers()
+	members, err := c.GetMembersContext(ctx)
 	if err != nil {
 		c.logger.Printf("[CollectChannels] obtaining members failed, %s", err)
-		return nil, err
+		return nil, nil, err
 	}
 	for _, c := range collectedChannels {
 		if c.IsDirectMessage {
@@ -194,138 +289,61 @@ func (c *Client) CollectChannels() ([]Channel, error) {
 		}
 		channels = append(channels, c)
 	}
-	return channels, nil
+	return channels, members, nil
 }
 
-func (c *Client) getChannels(method string) ([]Channel, error) {
-	res, err := c.get(method)
-	if err != nil {
-		return nil, err
+func (c *Client) getChannels(ctx context.Context, method string) ([]Channel, error) {
+	channels := []Channel{}
+	it := c.ChannelPagesContext(ctx, method)
+	for it.Next() {
+		channels = append(channels, it.Channels()...)
 	}
-	defer res.Body.Close()
-
-	parsed := &struct {
-		Ok       bool      `json:"ok"`
-		Error    string    `json:"error"`
-		Channels []Channel `json:"channels"`
-		Groups   []Channel `json:"groups"`
-		IMS      []Channel `json:"ims"`
-	}{}
-	if err := json.NewDecoder(res.Body).Decode(parsed); err != nil {
+	if err := it.Err(); err != nil {
 		return nil, err
 	}
-	if !parsed.Ok {
-		return nil, fmt.Errorf("request rejected by Slack, %s", parsed.Error)
-	}
-
-	if len(parsed.Channels) > 0 {
-		return parsed.Channels, nil
-	} else if len(parsed.Groups) > 0 {
-		return parsed.Groups, nil
-	} else if len(parsed.IMS) > 0 {
-		return parsed.IMS, nil
-	}
-	return []Channel{}, nil
+	return channels, nil
 }
 
-// SendMessage sends a message to a designated channel.
+// SendMessage sends a message to a designated channel, and returns the timestamp of the posted message.
+// The timestamp identifies the message, which can be passed to InThread to reply to it.
+// Pass WithBlocks or WithAttachments to decorate the message.
 // chat:write:user scope should be granted
 // See https://api.slack.com/methods/chat.postMessage
-func (c *Client) SendMessage(channelID, content string) error {
+func (c *Client) SendMessage(channelID, content string, opts ...MessageOption) (string, error) {
+	return c.SendMessageContext(context.Background(), channelID, content, opts...)
+}
+
+// SendMessageContext is SendMessage with a context to cancel the request.
+func (c *Client) SendMessageContext(ctx context.Context, channelID, content string, opts ...MessageOption) (string, error) {
 	v := url.Values{}
 	v.Set("channel", channelID)
 	v.Set("text", content)
 	v.Set("as_user", "true")
-	res, err := c.post("chat.postMessage", strings.NewReader(v.Encode()))
+	for _, opt := range opts {
+		if err := opt(v); err != nil {
+			c.logger.Printf("[SendMessage] applying option failed, %s", err)
+			return "", err
+		}
+	}
+	res, err := c.post(ctx, "chat.postMessage", strings.NewReader(v.Encode()))
 	if err != nil {
 		c.logger.Printf("[SendMessage] post failed, %s", err)
-		return err
+		return "", err
 	}
 	defer res.Body.Close()
 
 	parsed := &struct {
-		Ok    bool   `json:"ok"`
-		Error string `json:"error"`
+		apiResponse
+		TS string `json:"ts"`
 	}{}
 	if err := json.NewDecoder(res.Body).Decode(parsed); err != nil {
 		c.logger.Printf("[SendMessage] decoding json response failed, %s", err)
-		return err
-	}
-	if !parsed.Ok {
-		c.logger.Printf("[SendMessage] file upload request rejected by Slack, %s", parsed.Error)
-		return errors.New(parsed.Error)
-	}
-
-	return nil
-}
-
-// UploadFile uploads a file to a designated channel.
-// files:write:user scope should be granted.
-// See https://api.slack.com/methods/files.upload
-func (c *Client) UploadFile(channelID, filepath string, uploadOptions map[string]string) error {
-	bf := new(bytes.Buffer)
-	multiWriter := multipart.NewWriter(bf)
-
-	// write file
-	fileWriter, err := multiWriter.CreateFormFile("file", filepath)
-	if err != nil {
-		c.logger.Printf("[UploadFile] creating form-data for file failed, %s", err)
-		return err
-	}
-	f, err := os.Open(filepath)
-	if err != nil {
-		c.logger.Printf("[UploadFile] opening file failed, %s", err)
-		return err
-	}
-	defer f.Close()
-	if _, err := io.Copy(fileWriter, f); err != nil {
-		c.logger.Printf("[UploadFile] copying file contents to form writer failed, %s", err)
-		return err
+		return "", err
 	}
-
-	// add upload options
-	uploadOptions["channels"] = channelID
-	uploadOptions["token"] = c.token
-	for k, v := range uploadOptions {
-		w, err := multiWriter.CreateFormField(k)
-		if err != nil {
-			c.logger.Printf("[UploadFile] making %s field failed, %s", k, err)
-			return err
-		}
-		if _, err := io.Copy(w, strings.NewReader(v)); err != nil {
-			c.logger.Printf("[UploadFile] writing option %s:%s failed, %s", k, v, err)
-			return err
-		}
+	if err := parsed.err("chat.postMessage", res); err != nil {
+		c.logger.Printf("[SendMessage] request rejected by Slack, %s", err)
+		return "", err
 	}
 
-	contentType := multiWriter.FormDataContentType()
-	multiWriter.Close()
-
-	// post
-	url := c.buildURL("files.upload")
-	res, err := http.Post(url, contentType, bf)
-	if err != nil {
-		c.logger.Printf("[UploadFile] posting file failed, %s", err)
-		return err
-	}
-	if res.StatusCode != http.StatusOK {
-		c.logger.Printf("[UploadFile] response status %s", res.Status)
-		return fmt.Errorf("file upload post status, %s", res.Status)
-	}
-	defer res.Body.Close()
-
-	// parse and check respons
-	parsed := &struct {
-		Ok    bool   `json:"ok"`
-		Error string `json:"error"`
-	}{}
-	if err := json.NewDecoder(res.Body).Decode(parsed); err != nil {
-		c.logger.Printf("[UploadFile] decoding json response failed, %s", err)
-		return err
-	}
-	if !parsed.Ok {
-		c.logger.Printf("[UploadFile] file upload request rejected by Slack, %s", parsed.Error)
-		return errors.New(parsed.Error)
-	}
-	return nil
+	return parsed.TS, nil
 }
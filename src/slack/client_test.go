@@ -1,8 +1,12 @@
 package slack_test
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/matthewlujp/slack-cmd-client/src/slack"
 )
@@ -25,6 +29,7 @@ func TestObtainWorkspaceInfo(t *testing.T) {
 		Name:   "team1",
 		Domain: "team1-hoge",
 		Token:  validToken,
+		Scopes: []string{"team:read", "chat:write", "channels:read"},
 	}
 	if info, err := client.ObtainWorkspaceInfo(); err != nil {
 		t.Errorf("obtaining workspace failed, %s", err)
@@ -86,7 +91,7 @@ func TestCollectChannels(t *testing.T) {
 	expected := []slack.Channel{
 		slack.Channel{ID: "c1", Name: "channel1", Members: []string{"1", "2", "3"}, IsMember: true, Purpose: slack.Purpose{Value: "hoge 1"}},
 		slack.Channel{ID: "c3", Name: "channel3", Members: []string{"1", "2"}, IsMember: true, Purpose: slack.Purpose{Value: "hoge 3"}},
-		slack.Channel{ID: "c4", Name: "channel4", Members: []string{"1", "3"}, IsMember: true, Purpose: slack.Purpose{Value: "hoge 4"}},
+		slack.Channel{ID: "c4", Name: "channel4", Members: []string{"1", "3"}, IsMember: true, IsPrivate: true, Purpose: slack.Purpose{Value: "hoge 4"}},
 		slack.Channel{ID: "c5", Name: "jiro", IsDirectMessage: true, User: "2"},
 		slack.Channel{ID: "c6", Name: "fumino", IsDirectMessage: true, User: "3"},
 	}
@@ -97,6 +102,19 @@ func TestCollectChannels(t *testing.T) {
 		t.Errorf("on valid token, expected %v, got %v", expected, channels)
 	}
 
+	// members obtained to name direct messages are returned as well
+	expectedMembers := slack.Members{
+		slack.User{ID: "USLACKBOT", Name: "slackbot", RealName: "slackbot", IsBot: true},
+		slack.User{ID: "1", Name: "taro", RealName: "yamada taro", IsBot: false},
+		slack.User{ID: "2", Name: "jiro", RealName: "kayama jiro", IsBot: false},
+		slack.User{ID: "3", Name: "fumino", RealName: "kimura fumino", IsBot: false},
+	}
+	if channels, members, err := client.CollectChannelsAndMembersContext(context.Background()); err != nil {
+		t.Errorf("collecting channels and members failed, %s", err)
+	} else if !compareChannelSlice(channels, expected) || !compareMembers(members, expectedMembers) {
+		t.Errorf("expected %v and %v, got %v and %v", expected, expectedMembers, channels, members)
+	}
+
 	// raise error on invalid token
 	client, _ = slack.NewClient(invalidToken, nil, slack.BaseURL(server.URL))
 	if _, err := client.CollectChannels(); err == nil {
@@ -116,23 +134,41 @@ func TestSendMessage(t *testing.T) {
 
 	// valid token
 	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(server.URL))
-	if err := client.SendMessage(targetChannel, targetText); err != nil {
+	if ts, err := client.SendMessage(targetChannel, targetText); err != nil {
 		t.Errorf("sending message failed, %s", err)
+	} else if ts != targetTS {
+		t.Errorf("timestamp expected %s, got %s", targetTS, ts)
 	}
 
 	// raise error on invalid token
 	client, _ = slack.NewClient(invalidToken, nil, slack.BaseURL(server.URL))
-	if err := client.SendMessage(targetChannel, targetText); err == nil {
+	if _, err := client.SendMessage(targetChannel, targetText); err == nil {
 		t.Errorf("no error raised on invalid token")
 	}
 
 	// raise error on no scope
 	client, _ = slack.NewClient(validNoScopeToken, nil, slack.BaseURL(server.URL))
-	if err := client.SendMessage(targetChannel, targetText); err == nil {
+	if _, err := client.SendMessage(targetChannel, targetText); err == nil {
 		t.Errorf("no error raised on inadequate scope")
 	}
 }
 
+func TestSendMessageInThread(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(server.URL))
+	if _, err := client.SendMessage(targetChannel, targetText, slack.InThread(targetTS), slack.Broadcast()); err != nil {
+		t.Errorf("replying in thread failed, %s", err)
+	}
+	if _, err := client.SendMessage(targetChannel, targetText, slack.InThread("1.0")); err == nil {
+		t.Error("no error raised on unknown thread")
+	}
+	if _, err := client.SendMessage(targetChannel, targetText, slack.InThread("")); err == nil {
+		t.Error("no error raised on empty thread timestamp")
+	}
+}
+
 func TestUpload(t *testing.T) {
 	teardown := setup()
 	defer teardown()
@@ -161,3 +197,37 @@ func TestUpload(t *testing.T) {
 		t.Errorf("no error raised on inadequate scope")
 	}
 }
+
+func TestContextCancel(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	// cancelled before sending
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(server.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.ObtainWorkspaceInfoContext(ctx); err == nil {
+		t.Error("no error raised on cancelled context")
+	}
+	if _, err := client.SendMessageContext(ctx, targetChannel, targetText); err == nil {
+		t.Error("no error raised on cancelled context")
+	}
+
+	// deadline exceeded while server hangs
+	hang := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer s.Close()
+	defer close(hang)
+	client, _ = slack.NewClient(validToken, nil, slack.BaseURL(s.URL))
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.GetMembersContext(ctx); err == nil {
+		t.Error("no error raised on deadline")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request should be aborted on deadline, took %s", elapsed)
+	}
+}
@@ -0,0 +1,225 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a message in a channel or a thread
+type Message struct {
+	TS         string     `json:"ts"`
+	User       string     `json:"user"`
+	Username   string     `json:"username"` // name of bots
+	BotID      string     `json:"bot_id"`
+	Subtype    string     `json:"subtype"`
+	Text       string     `json:"text"`
+	ThreadTS   string     `json:"thread_ts"`
+	ReplyCount int        `json:"reply_count"`
+	Files      []File     `json:"files"`
+	Reactions  []Reaction `json:"reactions"`
+}
+
+// Time returns when the message was posted.
+func (m *Message) Time() time.Time {
+	t, _ := ParseTS(m.TS)
+	return t
+}
+
+// File holds info of a file shared in a message
+type File struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Title     string `json:"title"`
+	Mimetype  string `json:"mimetype"`
+	Filetype  string `json:"filetype"`
+	Size      int64  `json:"size"`
+	Permalink string `json:"permalink"`
+}
+
+// Reaction is an emoji reaction to a message
+type Reaction struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Users []string `json:"users"`
+}
+
+// String returns the reaction such as ":+1: 3".
+func (r Reaction) String() string {
+	return fmt.Sprintf(":%s: %d", r.Name, r.Count)
+}
+
+// ParseTS converts a message timestamp such as "1500000000.000100" into time.
+func ParseTS(ts string) (time.Time, error) {
+	sec, frac := ts, ""
+	if i := strings.Index(ts, "."); i >= 0 {
+		sec, frac = ts[:i], ts[i+1:]
+	}
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", ts)
+	}
+	var us int64
+	if frac != "" {
+		if us, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", ts)
+		}
+	}
+	return time.Unix(s, us*int64(time.Microsecond)), nil
+}
+
+// TimeToTS converts time into the timestamp format of messages.
+func TimeToTS(t time.Time) string {
+	return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/int(time.Microsecond))
+}
+
+// MessageIterator iterates over pages of messages.
+//
+//	it := c.HistoryPages(channelID, time.Time{}, time.Time{})
+//	for it.Next() {
+//		for _, m := range it.Messages() { ... }
+//	}
+//	if err := it.Err(); err != nil { ... }
+type MessageIterator struct {
+	p        *pager
+	messages []Message
+}
+
+// HistoryPages returns an iterator over pages of messages in a channel from the newest.
+// Messages are limited to those posted between oldest and latest, each of which is ignored if zero.
+// channels:history, groups:history, im:history, or mpim:history scope should be granted depending on the channel.
+// See https://api.slack.com/methods/conversations.history
+func (c *Client) HistoryPages(channelID string, oldest, latest time.Time) *MessageIterator {
+	return c.HistoryPagesContext(context.Background(), channelID, oldest, latest)
+}
+
+// HistoryPagesContext is HistoryPages with a context to cancel requests.
+func (c *Client) HistoryPagesContext(ctx context.Context, channelID string, oldest, latest time.Time) *MessageIterator {
+	params := url.Values{}
+	params.Set("channel", channelID)
+	if !oldest.IsZero() {
+		params.Set("oldest", TimeToTS(oldest))
+	}
+	if !latest.IsZero() {
+		params.Set("latest", TimeToTS(latest))
+	}
+	return &MessageIterator{p: c.newPager(ctx, "conversations.history", params)}
+}
+
+// Next fetches the next page. It returns false when there is no more page or an error occurred.
+func (it *MessageIterator) Next() bool {
+	parsed := &struct {
+		Messages []Message `json:"messages"`
+	}{}
+	if !it.p.next(parsed) {
+		it.messages = nil
+		return false
+	}
+	it.messages = parsed.Messages
+	return true
+}
+
+// Messages returns messages in the current page
+func (it *MessageIterator) Messages() []Message {
+	return it.messages
+}
+
+// Err returns an error occurred during iteration, if any
+func (it *MessageIterator) Err() error {
+	return it.p.err
+}
+
+// RepliesPages returns an iterator over pages of a thread, starting with the parent message with timestamp ts followed by replies in order.
+// channels:history, groups:history, im:history, or mpim:history scope should be granted depending on the channel.
+// See https://api.slack.com/methods/conversations.replies
+func (c *Client) RepliesPages(channelID, ts string) *MessageIterator {
+	return c.RepliesPagesContext(context.Background(), channelID, ts)
+}
+
+// RepliesPagesContext is RepliesPages with a context to cancel requests.
+func (c *Client) RepliesPagesContext(ctx context.Context, channelID, ts string) *MessageIterator {
+	params := url.Values{}
+	params.Set("channel", channelID)
+	params.Set("ts", ts)
+	return &MessageIterator{p: c.newPager(ctx, "conversations.replies", params)}
+}
+
+// History returns messages posted to a channel between oldest and latest, each of which is ignored if zero.
+// At most limit messages are returned from the newest, or all of them when limit is not positive.
+// Messages are ordered from the newest as Slack returns them.
+// channels:history, groups:history, im:history, or mpim:history scope should be granted depending on the channel.
+// See https://api.slack.com/methods/conversations.history
+func (c *Client) History(channelID string, oldest, latest time.Time, limit int) ([]Message, error) {
+	return c.HistoryContext(context.Background(), channelID, oldest, latest, limit)
+}
+
+// HistoryContext is History with a context to cancel requests.
+func (c *Client) HistoryContext(ctx context.Context, channelID string, oldest, latest time.Time, limit int) ([]Message, error) {
+	messages, err := collectMessages(c.HistoryPagesContext(ctx, channelID, oldest, latest), limit)
+	if err != nil {
+		c.logger.Printf("[History] request failed, %s", err)
+		return nil, err
+	}
+	return messages, nil
+}
+
+// collectMessages reads pages until limit messages are collected, asking no more than needed in each page.
+func collectMessages(it *MessageIterator, limit int) ([]Message, error) {
+	var messages []Message
+	for {
+		if limit > 0 {
+			left := limit - len(messages)
+			if left <= 0 {
+				break
+			}
+			if pageLimit, err := strconv.Atoi(it.p.params.Get("limit")); err != nil || left < pageLimit {
+				it.p.params.Set("limit", strconv.Itoa(left))
+			}
+		}
+		if !it.Next() {
+			break
+		}
+		messages = append(messages, it.Messages()...)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+// Replies returns a whole thread, which is the parent message with timestamp ts followed by replies in order.
+// channels:history, groups:history, im:history, or mpim:history scope should be granted depending on the channel.
+// See https://api.slack.com/methods/conversations.replies
+func (c *Client) Replies(channelID, ts string) ([]Message, error) {
+	return c.RepliesContext(context.Background(), channelID, ts)
+}
+
+// RepliesContext is Replies with a context to cancel requests.
+func (c *Client) RepliesContext(ctx context.Context, channelID, ts string) ([]Message, error) {
+	if ts == "" {
+		return nil, errors.New("empty thread timestamp")
+	}
+	messages, err := collectMessages(c.RepliesPagesContext(ctx, channelID, ts), 0)
+	if err != nil {
+		c.logger.Printf("[Replies] request failed, %s", err)
+		return nil, err
+	}
+
+	// the parent message is repeated at the beginning of every page
+	seen := make(map[string]bool, len(messages))
+	thread := messages[:0]
+	for _, m := range messages {
+		if !seen[m.TS] {
+			seen[m.TS] = true
+			thread = append(thread, m)
+		}
+	}
+	return thread, nil
+}
@@ -0,0 +1,93 @@
+package slack_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+func TestParseTS(t *testing.T) {
+	ts, err := slack.ParseTS(targetTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ts.Equal(time.Unix(1500000000, 100000)) {
+		t.Errorf("expected 1500000000.000100, got %v", ts)
+	}
+	if s := slack.TimeToTS(ts); s != targetTS {
+		t.Errorf("expected %s, got %s", targetTS, s)
+	}
+	if _, err := slack.ParseTS("hoge"); err == nil {
+		t.Error("no error raised on invalid timestamp")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	// all messages over pages of 2
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(server.URL), slack.PageLimit(2))
+	messages, err := client.History(targetChannel, time.Time{}, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("reading history failed, %s", err)
+	}
+	if len(messages) != len(historyMessages) {
+		t.Fatalf("expected %d messages, got %d", len(historyMessages), len(messages))
+	}
+	if messages[0].Text != "hogehoge" || messages[len(messages)-1].Text != "hoge" {
+		t.Errorf("messages should be ordered from the newest, got %v", messages)
+	}
+	if messages[2].Files[0].Name != "a.png" || messages[3].Reactions[0].Name != "+1" || messages[4].ReplyCount != 2 {
+		t.Errorf("files, reactions, or replies are not decoded, got %+v", messages)
+	}
+
+	// limit is applied across pages
+	messages, err = client.History(targetChannel, time.Time{}, time.Time{}, 3)
+	if err != nil {
+		t.Fatalf("reading history failed, %s", err)
+	}
+	if len(messages) != 3 || messages[2].Text != "bar" {
+		t.Errorf("expected the latest 3 messages, got %v", messages)
+	}
+
+	// messages between oldest and latest
+	oldest, _ := slack.ParseTS("1500000000.000100")
+	latest, _ := slack.ParseTS("1500000300.000100")
+	messages, err = client.History(targetChannel, oldest, latest, 0)
+	if err != nil {
+		t.Fatalf("reading history failed, %s", err)
+	}
+	if len(messages) != 2 || messages[0].Text != "bar" || messages[1].Text != "foo" {
+		t.Errorf("expected bar and foo, got %v", messages)
+	}
+
+	if _, err := client.History("unknown", time.Time{}, time.Time{}, 0); err == nil {
+		t.Error("no error raised on unknown channel")
+	}
+}
+
+func TestReplies(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(server.URL))
+	messages, err := client.Replies(targetChannel, targetTS)
+	if err != nil {
+		t.Fatalf("reading replies failed, %s", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("expected the parent and 2 replies over 3 pages, got %v", messages)
+	}
+	if messages[0].TS != targetTS || messages[1].Text != "reply1" || messages[2].Files[0].Name != "b.txt" {
+		t.Errorf("thread should be in order, got %+v", messages)
+	}
+
+	if _, err := client.Replies(targetChannel, "1400000000.000100"); err == nil {
+		t.Error("no error raised on unknown thread")
+	}
+	if _, err := client.Replies(targetChannel, ""); err == nil {
+		t.Error("no error raised on empty timestamp")
+	}
+}
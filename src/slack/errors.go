@@ -0,0 +1,123 @@
+package slack
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for common error codes returned by Slack.
+// Compare with errors.Is, which matches any *APIError with the same code.
+//
+//	if errors.Is(err, slack.ErrChannelNotFound) { ... }
+var (
+	ErrChannelNotFound = &APIError{Code: "channel_not_found"}
+	ErrNotInChannel    = &APIError{Code: "not_in_channel"}
+	ErrMissingScope    = &APIError{Code: "missing_scope"}
+	ErrInvalidAuth     = &APIError{Code: "invalid_auth"}
+	ErrNotAuthed       = &APIError{Code: "not_authed"}
+	ErrTokenRevoked    = &APIError{Code: "token_revoked"}
+	ErrAccountInactive = &APIError{Code: "account_inactive"}
+	ErrRateLimited     = &APIError{Code: "ratelimited"}
+)
+
+// APIError is an error returned by Slack web api, either as {"ok": false, "error": "..."} or as a non-200 http status.
+// Use errors.As to inspect it.
+//
+//	var apiErr *slack.APIError
+//	if errors.As(err, &apiErr) && apiErr.Code == "missing_scope" {
+//		fmt.Println("grant", apiErr.Needed)
+//	}
+type APIError struct {
+	Method     string        // api method such as chat.postMessage
+	Code       string        // error code such as channel_not_found, empty when the http request itself failed
+	Warning    string        // warning returned along with the error
+	Messages   []string      // detailed messages in response_metadata
+	Needed     string        // scope needed to call the method, on missing_scope
+	Provided   []string      // scopes the token has, on missing_scope
+	RetryAfter time.Duration // duration to wait before retry, on rate limiting
+	StatusCode int           // http status of the response
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString("slack: ")
+	if e.Method != "" {
+		fmt.Fprintf(&b, "%s: ", e.Method)
+	}
+	if e.Code != "" {
+		b.WriteString(e.Code)
+	} else {
+		fmt.Fprintf(&b, "response status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.Needed != "" {
+		fmt.Fprintf(&b, " (needed %s, provided %s)", e.Needed, strings.Join(e.Provided, ","))
+	}
+	if e.RetryAfter > 0 {
+		fmt.Fprintf(&b, " (retry after %s)", e.RetryAfter)
+	}
+	if len(e.Messages) > 0 {
+		fmt.Fprintf(&b, ": %s", strings.Join(e.Messages, "; "))
+	}
+	return b.String()
+}
+
+// Is reports whether target is an *APIError with the same error code, so that sentinel errors can be compared with errors.Is.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	if !ok {
+		return false
+	}
+	if t.Code == "" {
+		return t.StatusCode != 0 && t.StatusCode == e.StatusCode
+	}
+	return t.Code == e.Code
+}
+
+// apiResponse holds fields common to every Slack web api response.
+// Embed it in a struct to decode method specific fields as well.
+type apiResponse struct {
+	Ok               bool             `json:"ok"`
+	Error            string           `json:"error"`
+	Warning          string           `json:"warning"`
+	Needed           string           `json:"needed"`
+	Provided         string           `json:"provided"`
+	ResponseMetadata responseMetadata `json:"response_metadata"`
+}
+
+// err returns an *APIError if Slack rejected the request, or nil
+func (r *apiResponse) err(method string, res *http.Response) error {
+	if r.Ok {
+		return nil
+	}
+	e := &APIError{
+		Method:   method,
+		Code:     r.Error,
+		Warning:  r.Warning,
+		Messages: r.ResponseMetadata.Messages,
+		Needed:   r.Needed,
+	}
+	if r.Provided != "" {
+		e.Provided = strings.Split(r.Provided, ",")
+	}
+	if res != nil {
+		e.StatusCode = res.StatusCode
+		if d, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+			e.RetryAfter = d
+		}
+	}
+	return e
+}
+
+// statusError builds an *APIError from a non-200 response
+func statusError(method string, res *http.Response) error {
+	e := &APIError{Method: method, StatusCode: res.StatusCode}
+	if res.StatusCode == http.StatusTooManyRequests {
+		e.Code = ErrRateLimited.Code
+		if d, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+			e.RetryAfter = d
+		}
+	}
+	return e
+}
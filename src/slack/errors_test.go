@@ -0,0 +1,70 @@
+package slack_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+func TestAPIError(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	// missing scope
+	client, _ := slack.NewClient(validNoScopeToken, nil, slack.BaseURL(server.URL))
+	_, err := client.ObtainWorkspaceInfo()
+	if !errors.Is(err, slack.ErrMissingScope) {
+		t.Errorf("expected missing_scope, got %v", err)
+	}
+	var apiErr *slack.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error should be *slack.APIError, got %T", err)
+	}
+	expected := &slack.APIError{
+		Method:     "team.info",
+		Code:       "missing_scope",
+		Needed:     "team:read",
+		Provided:   []string{"identify", "bot"},
+		StatusCode: http.StatusOK,
+	}
+	if !reflect.DeepEqual(apiErr, expected) {
+		t.Errorf("expected %#v, got %#v", expected, apiErr)
+	}
+
+	// invalid token through paginated method
+	client, _ = slack.NewClient(invalidToken, nil, slack.BaseURL(server.URL))
+	if _, err := client.GetMembers(); !errors.Is(err, slack.ErrInvalidAuth) {
+		t.Errorf("expected invalid_auth, got %v", err)
+	}
+	if _, err := client.CollectChannels(); !errors.Is(err, slack.ErrInvalidAuth) {
+		t.Errorf("expected invalid_auth, got %v", err)
+	}
+
+	// unknown channel
+	client, _ = slack.NewClient(validToken, nil, slack.BaseURL(server.URL))
+	_, err = client.SendMessage("unknown", targetText)
+	if !errors.Is(err, slack.ErrChannelNotFound) {
+		t.Errorf("expected channel_not_found, got %v", err)
+	}
+	if errors.Is(err, slack.ErrNotInChannel) {
+		t.Error("channel_not_found should not match not_in_channel")
+	}
+}
+
+func TestAPIErrorOnStatus(t *testing.T) {
+	s, _ := flakyServer(http.StatusTooManyRequests, 10, http.Header{"Retry-After": []string{"0"}})
+	defer s.Close()
+
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL), slack.MaxRetries(0))
+	_, err := client.ObtainWorkspaceInfo()
+	if !errors.Is(err, slack.ErrRateLimited) {
+		t.Errorf("expected ratelimited, got %v", err)
+	}
+	var apiErr *slack.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %v", err)
+	}
+}
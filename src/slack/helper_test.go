@@ -11,11 +11,28 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/matthewlujp/slack-cmd-client/src/slack"
 )
 
+// historyMessages are messages in targetChannel from the oldest
+var historyMessages = []slack.Message{
+	{TS: "1500000000.000100", User: "1", Text: "hoge", ReplyCount: 2, ThreadTS: "1500000000.000100"},
+	{TS: "1500000100.000100", User: "2", Text: "foo", Reactions: []slack.Reaction{{Name: "+1", Count: 1, Users: []string{"1"}}}},
+	{TS: "1500000200.000100", User: "3", Text: "bar", Files: []slack.File{{ID: "F1", Name: "a.png"}}},
+	{TS: "1500000300.000100", BotID: "B1", Username: "ci", Text: "build passed"},
+	{TS: "1500000400.000100", User: "1", Text: "hogehoge"},
+}
+
+// threadMessages are the first message of historyMessages and its replies
+var threadMessages = []slack.Message{
+	historyMessages[0],
+	{TS: "1500000010.000100", User: "2", Text: "reply1", ThreadTS: targetTS},
+	{TS: "1500000020.000100", User: "3", Text: "reply2", ThreadTS: targetTS, Files: []slack.File{{ID: "F2", Name: "b.txt"}}},
+}
+
 const (
 	validToken           = "xoxo-valid-token1"
 	validNoScopeToken    = "xoxo-valid-noscope-token2"
@@ -25,6 +42,7 @@ const (
 	targetTitle          = "titel1"
 	filepath             = "./client.go"
 	targetInitialComment = "hoge"
+	targetTS             = "1500000000.000100"
 )
 
 var (
@@ -103,14 +121,18 @@ func authenticate(fn http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		w.Header().Set("Content-Type", "application/json")
+		// Slack tells scopes of the token in every response
+		w.Header().Set("X-OAuth-Scopes", "team:read, chat:write,channels:read")
 		w.WriteHeader(http.StatusOK)
 
 		// token check
 		if extractToken(r) == validNoScopeToken { // token with no adequate scope
 			json.NewEncoder(w).Encode(&struct {
-				Ok    bool   `json:"ok"`
-				Error string `json:"error"`
-			}{Ok: false, Error: "missing_scope"})
+				Ok       bool   `json:"ok"`
+				Error    string `json:"error"`
+				Needed   string `json:"needed"`
+				Provided string `json:"provided"`
+			}{Ok: false, Error: "missing_scope", Needed: "team:read", Provided: "identify,bot"})
 			return
 		} else if extractToken(r) != validToken { // invalid token
 			json.NewEncoder(w).Encode(&struct {
@@ -181,17 +203,47 @@ func setupHandlers(mux *http.ServeMux) {
 			RealName string `json:"real_name"`
 			IsBot    bool   `json:"is_bot"`
 		}
+		type metadata struct {
+			NextCursor string `json:"next_cursor"`
+		}
+		users := []user{
+			user{ID: "USLACKBOT", Name: "slackbot", RealName: "slackbot", IsBot: true},
+			user{ID: "1", Name: "taro", RealName: "yamada taro", IsBot: false},
+			user{ID: "2", Name: "jiro", RealName: "kayama jiro", IsBot: false},
+			user{ID: "3", Name: "fumino", RealName: "kimura fumino", IsBot: false},
+		}
+
+		// paginate members with limit and cursor
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 1 {
+			limit = len(users)
+		}
+		offset := 0
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			if offset, err = strconv.Atoi(cursor); err != nil || offset > len(users) {
+				json.NewEncoder(w).Encode(&struct {
+					Ok    bool   `json:"ok"`
+					Error string `json:"error"`
+				}{Ok: false, Error: "invalid_cursor"})
+				return
+			}
+		}
+		end := offset + limit
+		var nextCursor string
+		if end < len(users) {
+			nextCursor = strconv.Itoa(end)
+		} else {
+			end = len(users)
+		}
+
 		json.NewEncoder(w).Encode(&struct {
-			Ok      bool   `json:"ok"`
-			Members []user `json:"members"`
+			Ok               bool     `json:"ok"`
+			Members          []user   `json:"members"`
+			ResponseMetadata metadata `json:"response_metadata"`
 		}{
-			Ok: true,
-			Members: []user{
-				user{ID: "USLACKBOT", Name: "slackbot", RealName: "slackbot", IsBot: true},
-				user{ID: "1", Name: "taro", RealName: "yamada taro", IsBot: false},
-				user{ID: "2", Name: "jiro", RealName: "kayama jiro", IsBot: false},
-				user{ID: "3", Name: "fumino", RealName: "kimura fumino", IsBot: false},
-			},
+			Ok:               true,
+			Members:          users[offset:end],
+			ResponseMetadata: metadata{NextCursor: nextCursor},
 		})
 
 	})))
@@ -238,6 +290,96 @@ func setupHandlers(mux *http.ServeMux) {
 		})
 
 	})))
+	mux.HandleFunc("/conversations.history", checkRequestFormat("GET", "application/x-www-form-urlencoded", authenticate(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("channel") != targetChannel {
+			json.NewEncoder(w).Encode(&struct {
+				Ok    bool   `json:"ok"`
+				Error string `json:"error"`
+			}{Ok: false, Error: "channel_not_found"})
+			return
+		}
+
+		// messages between oldest and latest from the newest
+		var messages []slack.Message
+		for i := len(historyMessages) - 1; i >= 0; i-- {
+			m := historyMessages[i]
+			if oldest := query.Get("oldest"); oldest != "" && m.TS <= oldest {
+				continue
+			}
+			if latest := query.Get("latest"); latest != "" && m.TS >= latest {
+				continue
+			}
+			messages = append(messages, m)
+		}
+
+		// paginate with limit and cursor
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 {
+			limit = len(messages)
+		}
+		offset, _ := strconv.Atoi(query.Get("cursor"))
+		if offset > len(messages) {
+			offset = len(messages)
+		}
+		end := offset + limit
+		var nextCursor string
+		if end < len(messages) {
+			nextCursor = strconv.Itoa(end)
+		} else {
+			end = len(messages)
+		}
+		json.NewEncoder(w).Encode(&struct {
+			Ok               bool            `json:"ok"`
+			Messages         []slack.Message `json:"messages"`
+			ResponseMetadata struct {
+				NextCursor string `json:"next_cursor"`
+			} `json:"response_metadata"`
+		}{Ok: true, Messages: messages[offset:end], ResponseMetadata: struct {
+			NextCursor string `json:"next_cursor"`
+		}{NextCursor: nextCursor}})
+	})))
+	mux.HandleFunc("/conversations.replies", checkRequestFormat("GET", "application/x-www-form-urlencoded", authenticate(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("channel") != targetChannel {
+			json.NewEncoder(w).Encode(&struct {
+				Ok    bool   `json:"ok"`
+				Error string `json:"error"`
+			}{Ok: false, Error: "channel_not_found"})
+			return
+		}
+		if query.Get("ts") != targetTS {
+			json.NewEncoder(w).Encode(&struct {
+				Ok    bool   `json:"ok"`
+				Error string `json:"error"`
+			}{Ok: false, Error: "thread_not_found"})
+			return
+		}
+
+		// one reply per page, which is preceded by the parent message as Slack does
+		offset, _ := strconv.Atoi(query.Get("cursor"))
+		if offset >= len(threadMessages) {
+			offset = len(threadMessages) - 1
+		}
+		messages := []slack.Message{threadMessages[offset]}
+		if offset > 0 {
+			messages = append([]slack.Message{threadMessages[0]}, messages...)
+		}
+		var nextCursor string
+		if offset+1 < len(threadMessages) {
+			nextCursor = strconv.Itoa(offset + 1)
+		}
+		json.NewEncoder(w).Encode(&struct {
+			Ok               bool            `json:"ok"`
+			Messages         []slack.Message `json:"messages"`
+			HasMore          bool            `json:"has_more"`
+			ResponseMetadata struct {
+				NextCursor string `json:"next_cursor"`
+			} `json:"response_metadata"`
+		}{Ok: true, Messages: messages, HasMore: nextCursor != "", ResponseMetadata: struct {
+			NextCursor string `json:"next_cursor"`
+		}{NextCursor: nextCursor}})
+	})))
 	mux.HandleFunc("/groups.list", checkRequestFormat("GET", "application/x-www-form-urlencoded", authenticate(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(&struct {
 			Ok     bool      `json:"ok"`
@@ -297,12 +439,68 @@ func setupHandlers(mux *http.ServeMux) {
 			}{Ok: false, Error: "fatal_error"})
 			return
 		}
+		if ts := values.Get("thread_ts"); ts != "" && ts != targetTS {
+			serverLogger.Printf("thread expected %s, got %s", targetTS, ts)
+			json.NewEncoder(w).Encode(&struct {
+				Ok    bool   `json:"ok"`
+				Error string `json:"error"`
+			}{Ok: false, Error: "thread_not_found"})
+			return
+		}
+		if blocks := values.Get("blocks"); blocks != "" {
+			var parsed []map[string]interface{}
+			if err := json.Unmarshal([]byte(blocks), &parsed); err != nil || len(parsed) == 0 {
+				serverLogger.Printf("invalid blocks %s", blocks)
+				json.NewEncoder(w).Encode(&struct {
+					Ok    bool   `json:"ok"`
+					Error string `json:"error"`
+				}{Ok: false, Error: "invalid_blocks"})
+				return
+			}
+		}
 		json.NewEncoder(w).Encode(&struct {
-			Ok bool `json:"ok"`
-		}{Ok: true})
+			Ok      bool   `json:"ok"`
+			Channel string `json:"channel"`
+			TS      string `json:"ts"`
+		}{Ok: true, Channel: targetChannel, TS: targetTS})
 
 	})))
 
+	// chat.update and chat.delete accept only the message posted by chat.postMessage
+	editHandler := func(w http.ResponseWriter, r *http.Request) {
+		byteBody, _ := ioutil.ReadAll(r.Body)
+		values, _ := url.ParseQuery(string(byteBody))
+
+		if values.Get("channel") != targetChannel {
+			json.NewEncoder(w).Encode(&struct {
+				Ok    bool   `json:"ok"`
+				Error string `json:"error"`
+			}{Ok: false, Error: "channel_not_found"})
+			return
+		}
+		if values.Get("ts") != targetTS {
+			json.NewEncoder(w).Encode(&struct {
+				Ok    bool   `json:"ok"`
+				Error string `json:"error"`
+			}{Ok: false, Error: "message_not_found"})
+			return
+		}
+		if r.URL.Path == "/chat.update" && values.Get("text") != targetText {
+			json.NewEncoder(w).Encode(&struct {
+				Ok    bool   `json:"ok"`
+				Error string `json:"error"`
+			}{Ok: false, Error: "fatal_error"})
+			return
+		}
+		json.NewEncoder(w).Encode(&struct {
+			Ok      bool   `json:"ok"`
+			Channel string `json:"channel"`
+			TS      string `json:"ts"`
+		}{Ok: true, Channel: targetChannel, TS: targetTS})
+	}
+	mux.HandleFunc("/chat.update", checkRequestFormat("POST", "application/x-www-form-urlencoded", authenticate(editHandler)))
+	mux.HandleFunc("/chat.delete", checkRequestFormat("POST", "application/x-www-form-urlencoded", authenticate(editHandler)))
+
 	mux.HandleFunc("/files.upload", func(w http.ResponseWriter, r *http.Request) {
 		// check token
 		if token := r.FormValue("token"); token != validToken {
@@ -344,6 +542,15 @@ func setupHandlers(mux *http.ServeMux) {
 			return
 		}
 
+		if broadcast := r.FormValue("reply_broadcast"); broadcast != "" && (broadcast != "true" || r.FormValue("thread_ts") != targetTS) {
+			serverLogger.Printf("reply_broadcast should be true with thread %s, got %s", targetTS, broadcast)
+			json.NewEncoder(w).Encode(&struct {
+				Ok    bool   `json:"ok"`
+				Error string `json:"error"`
+			}{Ok: false, Error: "invalid_arguments"})
+			return
+		}
+
 		// check file content
 		uploadedFile, _, _ := r.FormFile("file")
 		defer uploadedFile.Close()
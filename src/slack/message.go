@@ -0,0 +1,96 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// MessageOption sets optional parameters of a message
+type MessageOption func(url.Values) error
+
+// InThread returns an option which posts a message as a reply in the thread of the parent message with timestamp ts.
+func InThread(ts string) MessageOption {
+	return func(v url.Values) error {
+		if ts == "" {
+			return errors.New("empty thread timestamp")
+		}
+		v.Set("thread_ts", ts)
+		return nil
+	}
+}
+
+// Broadcast returns an option which makes a reply in a thread visible in the channel as well.
+// It should be used along with InThread.
+func Broadcast() MessageOption {
+	return func(v url.Values) error {
+		v.Set("reply_broadcast", "true")
+		return nil
+	}
+}
+
+// UpdateMessage replaces text of a message with timestamp ts in a designated channel.
+// Only messages posted by the same user can be updated.
+// chat:write:user scope should be granted
+// See https://api.slack.com/methods/chat.update
+func (c *Client) UpdateMessage(channelID, ts, content string, opts ...MessageOption) error {
+	return c.UpdateMessageContext(context.Background(), channelID, ts, content, opts...)
+}
+
+// UpdateMessageContext is UpdateMessage with a context to cancel the request.
+func (c *Client) UpdateMessageContext(ctx context.Context, channelID, ts, content string, opts ...MessageOption) error {
+	v := url.Values{}
+	v.Set("channel", channelID)
+	v.Set("ts", ts)
+	v.Set("text", content)
+	v.Set("as_user", "true")
+	for _, opt := range opts {
+		if err := opt(v); err != nil {
+			c.logger.Printf("[UpdateMessage] applying option failed, %s", err)
+			return err
+		}
+	}
+	if err := c.callMessageMethod(ctx, "chat.update", v); err != nil {
+		c.logger.Printf("[UpdateMessage] request failed, %s", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteMessage deletes a message with timestamp ts in a designated channel.
+// Only messages posted by the same user can be deleted.
+// chat:write:user scope should be granted
+// See https://api.slack.com/methods/chat.delete
+func (c *Client) DeleteMessage(channelID, ts string) error {
+	return c.DeleteMessageContext(context.Background(), channelID, ts)
+}
+
+// DeleteMessageContext is DeleteMessage with a context to cancel the request.
+func (c *Client) DeleteMessageContext(ctx context.Context, channelID, ts string) error {
+	v := url.Values{}
+	v.Set("channel", channelID)
+	v.Set("ts", ts)
+	v.Set("as_user", "true")
+	if err := c.callMessageMethod(ctx, "chat.delete", v); err != nil {
+		c.logger.Printf("[DeleteMessage] request failed, %s", err)
+		return err
+	}
+	return nil
+}
+
+// callMessageMethod posts v to a chat method and checks the response
+func (c *Client) callMessageMethod(ctx context.Context, method string, v url.Values) error {
+	res, err := c.post(ctx, method, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	parsed := &apiResponse{}
+	if err := json.NewDecoder(res.Body).Decode(parsed); err != nil {
+		return err
+	}
+	return parsed.err(method, res)
+}
@@ -0,0 +1,49 @@
+package slack_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+func TestUpdateMessage(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(server.URL))
+	if err := client.UpdateMessage(targetChannel, targetTS, targetText); err != nil {
+		t.Errorf("updating message failed, %s", err)
+	}
+
+	// unknown message
+	var apiErr *slack.APIError
+	if err := client.UpdateMessage(targetChannel, "1.0", targetText); !errors.As(err, &apiErr) || apiErr.Code != "message_not_found" {
+		t.Errorf("expected message_not_found, got %v", err)
+	}
+
+	// raise error on invalid token
+	client, _ = slack.NewClient(invalidToken, nil, slack.BaseURL(server.URL))
+	if err := client.UpdateMessage(targetChannel, targetTS, targetText); !errors.Is(err, slack.ErrInvalidAuth) {
+		t.Errorf("expected invalid_auth, got %v", err)
+	}
+}
+
+func TestDeleteMessage(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(server.URL))
+	if err := client.DeleteMessage(targetChannel, targetTS); err != nil {
+		t.Errorf("deleting message failed, %s", err)
+	}
+	if err := client.DeleteMessage("unknown", targetTS); !errors.Is(err, slack.ErrChannelNotFound) {
+		t.Errorf("expected channel_not_found, got %v", err)
+	}
+
+	// raise error on no scope
+	client, _ = slack.NewClient(validNoScopeToken, nil, slack.BaseURL(server.URL))
+	if err := client.DeleteMessage(targetChannel, targetTS); !errors.Is(err, slack.ErrMissingScope) {
+		t.Errorf("expected missing_scope, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// DefaultPageLimit is the number of items requested per page from list methods
+	DefaultPageLimit = 200
+)
+
+// responseMetadata holds the cursor Slack returns for paginated methods, and detailed messages on errors
+type responseMetadata struct {
+	NextCursor string   `json:"next_cursor"`
+	Messages   []string `json:"messages"`
+	Warnings   []string `json:"warnings"`
+}
+
+// pager walks through the pages of a cursor-paginated api method.
+// See https://api.slack.com/docs/pagination
+type pager struct {
+	ctx    context.Context
+	client *Client
+	method string
+	params url.Values
+	cursor string
+	done   bool
+	err    error
+}
+
+func (c *Client) newPager(ctx context.Context, method string, params url.Values) *pager {
+	if params == nil {
+		params = url.Values{}
+	}
+	if c.pageLimit > 0 {
+		params.Set("limit", strconv.Itoa(c.pageLimit))
+	}
+	return &pager{ctx: ctx, client: c, method: method, params: params}
+}
+
+// next requests the following page and decodes it into v.
+// It returns false when all pages have been read or an error occurred.
+func (p *pager) next(v interface{}) bool {
+	if p.done || p.err != nil {
+		return false
+	}
+	if p.cursor != "" {
+		p.params.Set("cursor", p.cursor)
+	}
+
+	res, err := p.client.get(p.ctx, p.method, p.params)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	defer res.Body.Close()
+
+	parsed := &apiResponse{}
+	var raw json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil {
+		p.err = err
+		return false
+	}
+	if err := json.Unmarshal(raw, parsed); err != nil {
+		p.err = err
+		return false
+	}
+	if err := parsed.err(p.method, res); err != nil {
+		p.err = err
+		return false
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		p.err = err
+		return false
+	}
+
+	p.cursor = parsed.ResponseMetadata.NextCursor
+	if p.cursor == "" {
+		p.done = true
+	}
+	return true
+}
+
+// MemberIterator iterates over pages of workspace members.
+//
+//	it := c.MemberPages()
+//	for it.Next() {
+//		for _, u := range it.Members() { ... }
+//	}
+//	if err := it.Err(); err != nil { ... }
+type MemberIterator struct {
+	p       *pager
+	members Members
+}
+
+// MemberPages returns an iterator over pages of users.list.
+// users:read scope should be granted beforehand.
+// See https://api.slack.com/methods/users.list
+func (c *Client) MemberPages() *MemberIterator {
+	return c.MemberPagesContext(context.Background())
+}
+
+// MemberPagesContext is MemberPages with a context to cancel requests.
+func (c *Client) MemberPagesContext(ctx context.Context) *MemberIterator {
+	return &MemberIterator{p: c.newPager(ctx, "users.list", nil)}
+}
+
+// Next fetches the next page. It returns false when there is no more page or an error occurred.
+func (it *MemberIterator) Next() bool {
+	parsed := &struct {
+		Members Members `json:"members"`
+	}{}
+	if !it.p.next(parsed) {
+		it.members = nil
+		return false
+	}
+	it.members = parsed.Members
+	return true
+}
+
+// Members returns members in the current page
+func (it *MemberIterator) Members() Members {
+	return it.members
+}
+
+// Err returns an error occurred during iteration, if any
+func (it *MemberIterator) Err() error {
+	return it.p.err
+}
+
+// ChannelIterator iterates over pages of channels returned by a list method.
+type ChannelIterator struct {
+	p        *pager
+	channels []Channel
+}
+
+// ChannelPages returns an iterator over pages of a channel list method,
+// which is one of channels.list, conversations.list, groups.list, and im.list.
+func (c *Client) ChannelPages(method string) *ChannelIterator {
+	return c.ChannelPagesContext(context.Background(), method)
+}
+
+// ChannelPagesContext is ChannelPages with a context to cancel requests.
+func (c *Client) ChannelPagesContext(ctx context.Context, method string) *ChannelIterator {
+	return &ChannelIterator{p: c.newPager(ctx, method, nil)}
+}
+
+// Next fetches the next page. It returns false when there is no more page or an error occurred.
+func (it *ChannelIterator) Next() bool {
+	parsed := &struct {
+		Channels []Channel `json:"channels"`
+		Groups   []Channel `json:"groups"`
+		IMS      []Channel `json:"ims"`
+	}{}
+	if !it.p.next(parsed) {
+		it.channels = nil
+		return false
+	}
+
+	it.channels = make([]Channel, 0, len(parsed.Channels)+len(parsed.Groups)+len(parsed.IMS))
+	it.channels = append(it.channels, parsed.Channels...)
+	for _, g := range parsed.Groups {
+		// groups are private channels
+		g.IsPrivate = true
+		it.channels = append(it.channels, g)
+	}
+	it.channels = append(it.channels, parsed.IMS...)
+	return true
+}
+
+// Channels returns channels in the current page
+func (it *ChannelIterator) Channels() []Channel {
+	return it.channels
+}
+
+// Err returns an error occurred during iteration, if any
+func (it *ChannelIterator) Err() error {
+	return it.p.err
+}
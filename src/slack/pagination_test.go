@@ -0,0 +1,76 @@
+package slack_test
+
+import (
+	"testing"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+func TestPageLimit(t *testing.T) {
+	if _, err := slack.NewClient(validToken, nil, slack.PageLimit(0)); err == nil {
+		t.Error("no error is raised when a non-positive page limit is provided")
+	}
+}
+
+func TestMemberPages(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	// 4 members should be split into pages of 3 and 1
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(server.URL), slack.PageLimit(3))
+	var pageSizes []int
+	var members slack.Members
+	it := client.MemberPages()
+	for it.Next() {
+		pageSizes = append(pageSizes, len(it.Members()))
+		members = append(members, it.Members()...)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("iterating member pages failed, %s", err)
+	}
+	if len(pageSizes) != 2 || pageSizes[0] != 3 || pageSizes[1] != 1 {
+		t.Errorf("page sizes expected [3 1], got %v", pageSizes)
+	}
+	if len(members) != 4 {
+		t.Errorf("expected 4 members in total, got %d", len(members))
+	}
+	if it.Next() {
+		t.Error("iterator should not advance after the last page")
+	}
+
+	// GetMembers should follow cursors as well
+	client, _ = slack.NewClient(validToken, nil, slack.BaseURL(server.URL), slack.PageLimit(1))
+	if members, err := client.GetMembers(); err != nil {
+		t.Errorf("obtaining members failed, %s", err)
+	} else if len(members) != 4 {
+		t.Errorf("expected 4 members over 4 pages, got %v", members)
+	}
+
+	// error should be available from Err
+	client, _ = slack.NewClient(invalidToken, nil, slack.BaseURL(server.URL))
+	it = client.MemberPages()
+	if it.Next() {
+		t.Error("iterator advanced on invalid token")
+	}
+	if it.Err() == nil {
+		t.Error("no error raised on invalid token")
+	}
+}
+
+func TestChannelPages(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(server.URL))
+	it := client.ChannelPages("im.list")
+	var channels []slack.Channel
+	for it.Next() {
+		channels = append(channels, it.Channels()...)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("iterating channel pages failed, %s", err)
+	}
+	if len(channels) != 2 {
+		t.Errorf("expected 2 direct message channels, got %v", channels)
+	}
+}
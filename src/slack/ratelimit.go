@@ -0,0 +1,219 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the number of times a request is retried on rate limiting and transient failures
+	DefaultMaxRetries = 3
+	// DefaultBackoffBase is the first wait duration of exponential backoff
+	DefaultBackoffBase = time.Second
+	// DefaultBackoffMax is the upper bound of exponential backoff
+	DefaultBackoffMax = 30 * time.Second
+)
+
+// Tier is a rate limit tier which Slack assigns to each web api method.
+// See https://api.slack.com/docs/rate-limits
+type Tier int
+
+// Rate limit tiers
+const (
+	TierUnknown     Tier = iota
+	Tier1                // 1+ per minute
+	Tier2                // 20+ per minute
+	Tier3                // 50+ per minute
+	Tier4                // 100+ per minute
+	TierPostMessage      // chat.postMessage allows roughly 1 message per second
+)
+
+// methodTiers holds tiers of methods called by Client
+var methodTiers = map[string]Tier{
+	"team.info":             Tier3,
+	"users.list":            Tier2,
+	"channels.list":         Tier2,
+	"conversations.list":    Tier2,
+	"groups.list":           Tier3,
+	"im.list":               Tier2,
+	"chat.postMessage":      TierPostMessage,
+	"chat.update":           Tier3,
+	"conversations.history": Tier3,
+	"conversations.replies": Tier3,
+	"chat.delete":           Tier3,
+	"files.upload":          Tier2,
+
+	"files.getUploadURLExternal":   Tier4,
+	"files.completeUploadExternal": Tier4,
+	"apps.connections.open":        Tier1,
+}
+
+// perMinute returns the number of requests allowed in a minute
+func (t Tier) perMinute() float64 {
+	switch t {
+	case Tier1:
+		return 1
+	case Tier2:
+		return 20
+	case Tier3:
+		return 50
+	case Tier4:
+		return 100
+	case TierPostMessage:
+		return 60
+	}
+	return 0
+}
+
+// bucket is a token bucket which tracks the budget of a method
+type bucket struct {
+	tokens       float64
+	capacity     float64
+	rate         float64 // tokens per second
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// reserve takes a token and returns how long the caller should wait before sending a request.
+// Tokens may go negative so that concurrent callers queue up behind each other.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	var wait time.Duration
+	if now.Before(b.blockedUntil) {
+		wait = b.blockedUntil.Sub(now)
+		now = b.blockedUntil
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+	}
+
+	b.tokens--
+	if b.tokens < 0 {
+		wait += time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	return wait
+}
+
+// rateLimiter keeps per method budgets so that bulk operations throttle themselves before Slack rejects them
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*bucket)}
+}
+
+func (l *rateLimiter) bucket(method string, now time.Time) *bucket {
+	b, ok := l.buckets[method]
+	if !ok {
+		perMinute := methodTiers[method].perMinute()
+		if perMinute == 0 {
+			return nil
+		}
+		b = &bucket{tokens: perMinute, capacity: perMinute, rate: perMinute / 60, last: now}
+		l.buckets[method] = b
+	}
+	return b
+}
+
+// reserve returns how long to wait before calling method
+func (l *rateLimiter) reserve(method string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if b := l.bucket(method, now); b != nil {
+		return b.reserve(now)
+	}
+	return 0
+}
+
+// penalize blocks method for d after Slack answered 429.
+// A single token is left so that the rejected request can be retried as soon as the block ends.
+func (l *rateLimiter) penalize(method string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if b := l.bucket(method, now); b != nil {
+		b.tokens = 1
+		if until := now.Add(d); until.After(b.blockedUntil) {
+			b.blockedUntil = until
+		}
+	}
+}
+
+// backoff returns the wait duration before the next attempt, which grows exponentially with jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.backoffBase << uint(attempt)
+	if d <= 0 || d > c.backoffMax {
+		d = c.backoffMax
+	}
+	if half := d / 2; half > 0 {
+		d = half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+	return d
+}
+
+// parseRetryAfter reads Retry-After header, which is either seconds or an http date
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec >= 0 {
+		return time.Duration(sec) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// idempotentMethods are api methods called with POST which change nothing visible when called twice
+var idempotentMethods = map[string]bool{
+	"apps.connections.open":      true,
+	"files.getUploadURLExternal": true,
+}
+
+// isIdempotent reports whether a request of requestMethod to an api method can be sent again
+// after Slack may have handled it. Methods posting, updating, or deleting messages and files are not.
+func isIdempotent(requestMethod, method string) bool {
+	return requestMethod == http.MethodGet || idempotentMethods[method]
+}
+
+// isTransient reports whether a request failed for a reason which may disappear on retry
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// sleep waits for d, or returns the context error when ctx is done earlier
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
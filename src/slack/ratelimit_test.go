@@ -0,0 +1,178 @@
+package slack_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+// flakyServer returns a server whose team.info fails with the given status for the first failures requests
+func flakyServer(status, failures int, header http.Header) (*httptest.Server, *int32) {
+	var count int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) <= int32(failures) {
+			for k, vs := range header {
+				for _, v := range vs {
+					w.Header().Add(k, v)
+				}
+			}
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(&struct {
+			Ok   bool            `json:"ok"`
+			Team slack.Workspace `json:"team"`
+		}{Ok: true, Team: slack.Workspace{ID: "1234", Name: "team1"}})
+	}))
+	return s, &count
+}
+
+func TestRetryOnTooManyRequests(t *testing.T) {
+	s, count := flakyServer(http.StatusTooManyRequests, 2, http.Header{"Retry-After": []string{"0"}})
+	defer s.Close()
+
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL))
+	if _, err := client.ObtainWorkspaceInfo(); err != nil {
+		t.Errorf("request should succeed after retries, %s", err)
+	}
+	if n := atomic.LoadInt32(count); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestRetryAfterIsHonored(t *testing.T) {
+	s, _ := flakyServer(http.StatusTooManyRequests, 1, http.Header{"Retry-After": []string{"1"}})
+	defer s.Close()
+
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL))
+	start := time.Now()
+	if _, err := client.ObtainWorkspaceInfo(); err != nil {
+		t.Errorf("request should succeed after retry, %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retry should wait for Retry-After, waited only %s", elapsed)
+	}
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	s, count := flakyServer(http.StatusServiceUnavailable, 2, nil)
+	defer s.Close()
+
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL), slack.RetryBackoff(time.Millisecond, 5*time.Millisecond))
+	if _, err := client.ObtainWorkspaceInfo(); err != nil {
+		t.Errorf("request should succeed after retries, %s", err)
+	}
+	if n := atomic.LoadInt32(count); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestMaxRetries(t *testing.T) {
+	s, count := flakyServer(http.StatusInternalServerError, 10, nil)
+	defer s.Close()
+
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL), slack.MaxRetries(2), slack.RetryBackoff(time.Millisecond, 5*time.Millisecond))
+	if _, err := client.ObtainWorkspaceInfo(); err == nil {
+		t.Error("no error raised after retries are exhausted")
+	}
+	if n := atomic.LoadInt32(count); n != 3 {
+		t.Errorf("expected 1 request and 2 retries, got %d requests", n)
+	}
+
+	// client errors are not retried
+	s, count = flakyServer(http.StatusBadRequest, 10, nil)
+	defer s.Close()
+	client, _ = slack.NewClient(validToken, nil, slack.BaseURL(s.URL))
+	if _, err := client.ObtainWorkspaceInfo(); err == nil {
+		t.Error("no error raised on 400")
+	}
+	if n := atomic.LoadInt32(count); n != 1 {
+		t.Errorf("400 should not be retried, got %d requests", n)
+	}
+
+	if _, err := slack.NewClient(validToken, nil, slack.MaxRetries(-1)); err == nil {
+		t.Error("no error raised on negative max retries")
+	}
+}
+
+func TestNoRetryAfterPosting(t *testing.T) {
+	// a message may have been posted when Slack answers 5xx
+	s, count := flakyServer(http.StatusServiceUnavailable, 2, nil)
+	defer s.Close()
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL), slack.RetryBackoff(time.Millisecond, 5*time.Millisecond))
+	if _, err := client.SendMessage(targetChannel, targetText); err == nil {
+		t.Error("no error raised on 503")
+	}
+	if n := atomic.LoadInt32(count); n != 1 {
+		t.Errorf("posting a message should not be retried on 503, got %d requests", n)
+	}
+
+	// but not when Slack answers 429
+	s, count = flakyServer(http.StatusTooManyRequests, 2, http.Header{"Retry-After": []string{"0"}})
+	defer s.Close()
+	client, _ = slack.NewClient(validToken, nil, slack.BaseURL(s.URL))
+	if _, err := client.SendMessage(targetChannel, targetText); err != nil {
+		t.Errorf("request should succeed after retries, %s", err)
+	}
+	if n := atomic.LoadInt32(count); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestNoRetryAfterLostResponse(t *testing.T) {
+	// server which drops the connection after reading the first request
+	var count int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		json.NewEncoder(w).Encode(&struct {
+			Ok   bool            `json:"ok"`
+			Team slack.Workspace `json:"team"`
+		}{Ok: true, Team: slack.Workspace{ID: "1234", Name: "team1"}})
+	}))
+	defer s.Close()
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL), slack.RetryBackoff(time.Millisecond, 5*time.Millisecond))
+
+	if _, err := client.SendMessage(targetChannel, targetText); err == nil {
+		t.Error("no error raised on a lost response")
+	}
+	if n := atomic.LoadInt32(&count); n != 1 {
+		t.Errorf("posting a message should not be retried once sent, got %d requests", n)
+	}
+
+	// reading is retried
+	atomic.StoreInt32(&count, 0)
+	if _, err := client.ObtainWorkspaceInfo(); err != nil {
+		t.Errorf("request should succeed after retry, %s", err)
+	}
+	if n := atomic.LoadInt32(&count); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestRetryBeforeSending(t *testing.T) {
+	// nothing listens on the address, so that the request is never sent
+	s := httptest.NewServer(http.NotFoundHandler())
+	baseURL := s.URL
+	s.Close()
+
+	var logs bytes.Buffer
+	client, _ := slack.NewClient(validToken, log.New(&logs, "", 0), slack.BaseURL(baseURL), slack.MaxRetries(2), slack.RetryBackoff(time.Millisecond, 5*time.Millisecond))
+	if _, err := client.SendMessage(targetChannel, targetText); err == nil {
+		t.Error("no error raised on refused connection")
+	}
+	if n := strings.Count(logs.String(), "chat.postMessage failed"); n != 2 {
+		t.Errorf("posting a message should be retried while it is not sent, got %d retries\n%s", n, logs.String())
+	}
+}
@@ -0,0 +1,291 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Envelope types of Socket Mode
+const (
+	EnvelopeHello         = "hello"
+	EnvelopeDisconnect    = "disconnect"
+	EnvelopeEventsAPI     = "events_api"
+	EnvelopeSlashCommands = "slash_commands"
+	EnvelopeInteractive   = "interactive"
+)
+
+var (
+	// ErrSocketModeDisabled is returned by Run when Socket Mode is turned off for the app
+	ErrSocketModeDisabled = errors.New("slack: socket mode is disabled for the app")
+
+	// errRefreshRequested tells that Slack is about to close the connection, so that a new one should be opened
+	errRefreshRequested = errors.New("slack: socket mode connection refresh requested")
+)
+
+// Envelope is a frame which Slack sends over a Socket Mode connection
+// See https://api.slack.com/apis/connections/socket-implement
+type Envelope struct {
+	EnvelopeID             string          `json:"envelope_id"`
+	Type                   string          `json:"type"`
+	Payload                json.RawMessage `json:"payload"`
+	AcceptsResponsePayload bool            `json:"accepts_response_payload"`
+	RetryAttempt           int             `json:"retry_attempt"`
+	RetryReason            string          `json:"retry_reason"`
+	Reason                 string          `json:"reason"`          // disconnect only
+	NumConnections         int             `json:"num_connections"` // hello only
+}
+
+// EventsAPIEvent is an event of Events API delivered in an events_api envelope
+// See https://api.slack.com/apis/connections/events-api#callback-field
+type EventsAPIEvent struct {
+	TeamID    string          `json:"team_id"`
+	APIAppID  string          `json:"api_app_id"`
+	EventID   string          `json:"event_id"`
+	EventTime int64           `json:"event_time"`
+	Type      string          `json:"-"`     // type of the inner event such as "message"
+	Event     json.RawMessage `json:"event"` // inner event, which can be decoded into a typed event
+}
+
+// MessageEvent is a message posted, edited, or deleted in a channel.
+// Edits and deletions have Subtype of "message_changed" and "message_deleted".
+// See https://api.slack.com/events/message
+type MessageEvent struct {
+	Type            string        `json:"type"`
+	Subtype         string        `json:"subtype"`
+	Channel         string        `json:"channel"`
+	ChannelType     string        `json:"channel_type"`
+	User            string        `json:"user"`
+	BotID           string        `json:"bot_id"`
+	Username        string        `json:"username"` // name of bots
+	Text            string        `json:"text"`
+	TS              string        `json:"ts"`
+	ThreadTS        string        `json:"thread_ts"`
+	EventTS         string        `json:"event_ts"`
+	Hidden          bool          `json:"hidden"`
+	DeletedTS       string        `json:"deleted_ts"`       // message_deleted only
+	Message         *MessageEvent `json:"message"`          // new message of message_changed
+	PreviousMessage *MessageEvent `json:"previous_message"` // message_changed and message_deleted
+}
+
+// SocketModeClient receives events over a WebSocket connection instead of a public http endpoint.
+// Register handlers, then call Run.
+// Handlers are called one by one in the order events arrive.
+// See https://api.slack.com/apis/connections/socket
+type SocketModeClient struct {
+	client *Client
+	dialer *websocket.Dialer
+	logger *log.Logger
+
+	connectHandlers []func()
+	messageHandlers []func(*MessageEvent)
+	eventHandlers   map[string][]func(*EventsAPIEvent)
+
+	writeMu sync.Mutex // WebSocket connections accept one writer at a time
+}
+
+// NewSocketModeClient returns a Socket Mode client.
+// appToken is an app-level token starting with "xapp-" which has connections:write scope.
+// Options of Client such as BaseURL and RetryBackoff apply to apps.connections.open and reconnection.
+func NewSocketModeClient(appToken string, logger *log.Logger, opts ...Option) (*SocketModeClient, error) {
+	c, err := NewClient(appToken, logger, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &SocketModeClient{
+		client:        c,
+		dialer:        websocket.DefaultDialer,
+		logger:        c.logger,
+		eventHandlers: make(map[string][]func(*EventsAPIEvent)),
+	}, nil
+}
+
+// HandleConnect registers f called whenever a connection is established, including reconnection.
+func (s *SocketModeClient) HandleConnect(f func()) {
+	s.connectHandlers = append(s.connectHandlers, f)
+}
+
+// HandleMessage registers f called with each message event.
+// The app should subscribe message.channels, message.groups, message.im, or message.mpim events.
+func (s *SocketModeClient) HandleMessage(f func(*MessageEvent)) {
+	s.messageHandlers = append(s.messageHandlers, f)
+}
+
+// HandleEvent registers f called with each event of Events API of eventType, such as "reaction_added".
+// Pass "*" to receive all events.
+func (s *SocketModeClient) HandleEvent(eventType string, f func(*EventsAPIEvent)) {
+	s.eventHandlers[eventType] = append(s.eventHandlers[eventType], f)
+}
+
+// Run connects to Slack and dispatches events to handlers until ctx is done.
+// Envelopes are acknowledged as soon as they arrive.
+// The connection is reopened when Slack asks to refresh it, and with backoff when it is lost.
+// Run returns an error only when ctx is done, the token is rejected, or Socket Mode is disabled.
+func (s *SocketModeClient) Run(ctx context.Context) error {
+	attempt := 0
+	for {
+		connected, err := s.connect(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if isFatalSocketModeError(err) {
+			s.logger.Printf("[Run] giving up, %s", err)
+			return err
+		}
+		if connected {
+			attempt = 0
+		}
+		if err == errRefreshRequested {
+			s.logger.Print("[Run] reconnecting on request of Slack")
+			continue
+		}
+
+		wait := s.client.backoff(attempt)
+		attempt++
+		s.logger.Printf("[Run] connection lost, reconnecting in %s, %s", wait, err)
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// connect opens a connection and serves it until it is closed.
+// connected tells whether Slack said hello, which means the connection worked.
+func (s *SocketModeClient) connect(ctx context.Context) (connected bool, err error) {
+	wsURL, err := s.openConnection(ctx)
+	if err != nil {
+		return false, err
+	}
+	conn, _, err := s.dialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	// unblock reading when ctx is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		var env Envelope
+		if err := conn.ReadJSON(&env); err != nil {
+			return connected, err
+		}
+		if env.EnvelopeID != "" {
+			if err := s.ack(conn, env.EnvelopeID); err != nil {
+				return connected, err
+			}
+		}
+
+		switch env.Type {
+		case EnvelopeHello:
+			connected = true
+			for _, f := range s.connectHandlers {
+				f()
+			}
+		case EnvelopeDisconnect:
+			if env.Reason == "link_disabled" {
+				return connected, ErrSocketModeDisabled
+			}
+			return connected, errRefreshRequested
+		case EnvelopeEventsAPI:
+			if err := s.dispatch(env.Payload); err != nil {
+				s.logger.Printf("[connect] dispatching event failed, %s", err)
+			}
+		default:
+			s.logger.Printf("[connect] ignoring %s envelope", env.Type)
+		}
+	}
+}
+
+// openConnection obtains a WebSocket url.
+// See https://api.slack.com/methods/apps.connections.open
+func (s *SocketModeClient) openConnection(ctx context.Context) (string, error) {
+	res, err := s.client.post(ctx, "apps.connections.open", strings.NewReader(""))
+	if err != nil {
+		s.logger.Printf("[openConnection] request failed, %s", err)
+		return "", err
+	}
+	defer res.Body.Close()
+
+	parsed := &struct {
+		apiResponse
+		URL string `json:"url"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(parsed); err != nil {
+		s.logger.Printf("[openConnection] decoding json response failed, %s", err)
+		return "", err
+	}
+	if err := parsed.err("apps.connections.open", res); err != nil {
+		s.logger.Printf("[openConnection] request rejected by Slack, %s", err)
+		return "", err
+	}
+	return parsed.URL, nil
+}
+
+// ack acknowledges an envelope, without which Slack sends it again.
+func (s *SocketModeClient) ack(conn *websocket.Conn, envelopeID string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return conn.WriteJSON(&struct {
+		EnvelopeID string `json:"envelope_id"`
+	}{EnvelopeID: envelopeID})
+}
+
+// dispatch decodes the payload of an events_api envelope and calls handlers of the event type.
+func (s *SocketModeClient) dispatch(payload json.RawMessage) error {
+	e := &EventsAPIEvent{}
+	if err := json.Unmarshal(payload, e); err != nil {
+		return err
+	}
+	typ, err := peekType(e.Event)
+	if err != nil {
+		return fmt.Errorf("event %s: %s", e.EventID, err)
+	}
+	e.Type = typ
+
+	if typ == "message" && len(s.messageHandlers) > 0 {
+		m := &MessageEvent{}
+		if err := json.Unmarshal(e.Event, m); err != nil {
+			return fmt.Errorf("event %s: %s", e.EventID, err)
+		}
+		for _, f := range s.messageHandlers {
+			f(m)
+		}
+	}
+	for _, f := range s.eventHandlers[typ] {
+		f(e)
+	}
+	for _, f := range s.eventHandlers["*"] {
+		f(e)
+	}
+	return nil
+}
+
+// isFatalSocketModeError tells whether reconnecting is pointless.
+func isFatalSocketModeError(err error) bool {
+	if err == ErrSocketModeDisabled {
+		return true
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Code == "not_allowed_token_type" || apiErr.Code == "invalid_arguments" {
+		return true
+	}
+	return errors.Is(err, ErrInvalidAuth) || errors.Is(err, ErrNotAuthed) || errors.Is(err, ErrTokenRevoked) || errors.Is(err, ErrAccountInactive) || errors.Is(err, ErrMissingScope)
+}
@@ -0,0 +1,183 @@
+package slack_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+const validAppToken = "xapp-valid-token"
+
+// socketModeServer is a stand-in for apps.connections.open and the WebSocket endpoint.
+// Each connection says hello, sends an envelope per script entry, and then asks to refresh the connection.
+type socketModeServer struct {
+	*httptest.Server
+	scripts [][]string // envelopes sent on each connection
+	drops   int        // number of connections closed without notice first
+
+	mu          sync.Mutex
+	connections int
+	acks        []string
+}
+
+func newSocketModeServer(scripts ...[]string) *socketModeServer {
+	s := &socketModeServer{scripts: scripts}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+validAppToken {
+			json.NewEncoder(w).Encode(&struct {
+				Ok    bool   `json:"ok"`
+				Error string `json:"error"`
+			}{Ok: false, Error: "invalid_auth"})
+			return
+		}
+		json.NewEncoder(w).Encode(&struct {
+			Ok  bool   `json:"ok"`
+			URL string `json:"url"`
+		}{Ok: true, URL: "ws" + strings.TrimPrefix(s.URL, "http") + "/link"})
+	})
+	mux.HandleFunc("/link", s.serveLink)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *socketModeServer) serveLink(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	s.mu.Lock()
+	n := s.connections - s.drops
+	s.connections++
+	s.mu.Unlock()
+	if n < 0 {
+		return
+	}
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"hello","num_connections":1}`))
+	if n >= len(s.scripts) {
+		// keep the last connection open until the client leaves
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}
+	for i, envelope := range s.scripts[n] {
+		conn.WriteMessage(websocket.TextMessage, []byte(envelope))
+		if !strings.Contains(envelope, "envelope_id") {
+			continue
+		}
+		var ack struct {
+			EnvelopeID string `json:"envelope_id"`
+		}
+		if err := conn.ReadJSON(&ack); err != nil {
+			serverLogger.Printf("reading ack of envelope %d failed, %s", i, err)
+			return
+		}
+		s.mu.Lock()
+		s.acks = append(s.acks, ack.EnvelopeID)
+		s.mu.Unlock()
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"disconnect","reason":"refresh_requested"}`))
+}
+
+func eventsAPIEnvelope(envelopeID, event string) string {
+	return fmt.Sprintf(`{"envelope_id":"%s","type":"events_api","payload":{"team_id":"T1","event_id":"Ev%s","event":%s}}`, envelopeID, envelopeID, event)
+}
+
+func TestSocketModeRun(t *testing.T) {
+	s := newSocketModeServer(
+		[]string{
+			eventsAPIEnvelope("1", `{"type":"message","channel":"c1","user":"u1","text":"hoge","ts":"1.0"}`),
+			eventsAPIEnvelope("2", `{"type":"reaction_added","user":"u1","reaction":"+1"}`),
+		},
+		[]string{
+			`{"type":"unknown_envelope"}`,
+			eventsAPIEnvelope("3", `{"type":"message","channel":"c1","user":"u2","text":"foo","ts":"2.0","thread_ts":"1.0"}`),
+		},
+	)
+	defer s.Close()
+
+	client, err := slack.NewSocketModeClient(validAppToken, nil, slack.BaseURL(s.URL), slack.DisableThrottling())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var messages []*slack.MessageEvent
+	var events []string
+	connects := 0
+	client.HandleConnect(func() { connects++ })
+	client.HandleMessage(func(m *slack.MessageEvent) {
+		messages = append(messages, m)
+		if len(messages) == 2 {
+			cancel()
+		}
+	})
+	client.HandleEvent("reaction_added", func(e *slack.EventsAPIEvent) { events = append(events, e.EventID) })
+
+	if err := client.Run(ctx); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if len(messages) != 2 || messages[0].Text != "hoge" || messages[1].User != "u2" || messages[1].ThreadTS != "1.0" {
+		t.Fatalf("unexpected messages %+v", messages)
+	}
+	if len(events) != 1 || events[0] != "Ev2" {
+		t.Errorf("expected reaction_added event Ev2, got %v", events)
+	}
+	if connects != 2 {
+		t.Errorf("expected to reconnect once on refresh request, got %d connections", connects)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the last ack may be still on the way when the client is cancelled
+	if len(s.acks) < 2 || s.acks[0] != "1" || s.acks[1] != "2" {
+		t.Errorf("envelopes should be acknowledged, got %v", s.acks)
+	}
+}
+
+func TestSocketModeReconnect(t *testing.T) {
+	// connections are dropped without notice twice
+	s := newSocketModeServer()
+	s.drops = 2
+	defer s.Close()
+
+	client, _ := slack.NewSocketModeClient(validAppToken, nil, slack.BaseURL(s.URL), slack.DisableThrottling(), slack.RetryBackoff(time.Millisecond, 5*time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client.HandleConnect(cancel)
+	if err := client.Run(ctx); err != context.Canceled {
+		t.Errorf("expected to reconnect and be cancelled, got %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.connections != 3 {
+		t.Errorf("expected 3 connections, got %d", s.connections)
+	}
+}
+
+func TestSocketModeInvalidToken(t *testing.T) {
+	s := newSocketModeServer()
+	defer s.Close()
+
+	client, _ := slack.NewSocketModeClient(invalidToken, nil, slack.BaseURL(s.URL), slack.DisableThrottling())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Run(ctx); err == nil || err == context.DeadlineExceeded {
+		t.Errorf("expected to give up on invalid token, got %v", err)
+	}
+}
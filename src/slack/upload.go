@@ -0,0 +1,370 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// UploadOptions holds optional parameters of a file upload.
+type UploadOptions struct {
+	Title          string // title of the file
+	InitialComment string // message posted along with the file
+	Filetype       string // file type identifier such as "text" or "png", guessed by Slack if empty
+	ThreadTS       string // timestamp of a parent message to upload the file as a reply in its thread
+	Broadcast      bool   // show the reply in the channel as well, used with ThreadTS
+
+	// Size is the length of the content in bytes.
+	// If zero, it is obtained from the reader when possible.
+	Size int64
+
+	// Progress is called whenever a chunk of the content is sent, if not nil.
+	// Counts start over when the upload is retried.
+	Progress func(UploadProgress)
+
+	// Spool copies contents of unknown size to a temporary file to learn the length,
+	// so that they can be uploaded with the external upload flow, which requires it in advance.
+	// Otherwise such contents are streamed with files.upload.
+	Spool bool
+}
+
+// UploadProgress reports how much of the content has been sent
+type UploadProgress struct {
+	Sent       int64   // bytes sent so far
+	Total      int64   // length of the content, -1 if unknown
+	Throughput float64 // bytes per second
+}
+
+// UploadMethod selects which api a Client uses to upload files
+type UploadMethod int
+
+// Upload methods
+const (
+	// UploadAuto uses the external upload flow and falls back to files.upload when it is not available
+	UploadAuto UploadMethod = iota
+	// UploadExternal uses files.getUploadURLExternal and files.completeUploadExternal
+	UploadExternal
+	// UploadLegacy uses files.upload, which Slack has deprecated for new apps
+	UploadLegacy
+)
+
+// UploadFile uploads a file to a designated channel.
+// title, initial_comment, filetype, thread_ts, and reply_broadcast keys in uploadOptions are sent along with the file.
+// The file is streamed, so that it is never held in memory as a whole.
+// files:write:user scope should be granted.
+// See https://api.slack.com/methods/files.upload
+func (c *Client) UploadFile(channelID, filepath string, uploadOptions map[string]string) error {
+	return c.UploadFileContext(context.Background(), channelID, filepath, uploadOptions)
+}
+
+// UploadFileContext is UploadFile with a context to cancel the upload.
+func (c *Client) UploadFileContext(ctx context.Context, channelID, path string, uploadOptions map[string]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		c.logger.Printf("[UploadFile] opening file failed, %s", err)
+		return err
+	}
+	defer f.Close()
+
+	opts := UploadOptions{
+		Title:          uploadOptions["title"],
+		InitialComment: uploadOptions["initial_comment"],
+		Filetype:       uploadOptions["filetype"],
+		ThreadTS:       uploadOptions["thread_ts"],
+		Broadcast:      uploadOptions["reply_broadcast"] == "true",
+	}
+	return c.UploadReader(ctx, channelID, filepath.Base(path), f, opts)
+}
+
+// UploadReader uploads contents read from r to a designated channel as a file named name.
+// r can be anything such as os.Stdin.
+// Which api is used depends on UploadVia option, and the external upload flow is tried first by default.
+// Since the flow requires the length of the content in advance, contents of unknown size are streamed
+// with files.upload instead, unless opts.Spool is set to copy them to a temporary file first.
+// The upload is retried on rate limiting and on failures before it is sent only when r implements io.Seeker.
+// files:write:user scope should be granted.
+// See https://api.slack.com/messaging/files#uploading_files
+func (c *Client) UploadReader(ctx context.Context, channelID, name string, r io.Reader, opts UploadOptions) error {
+	if c.uploadMethod == UploadLegacy {
+		return c.uploadLegacy(ctx, channelID, name, r, opts)
+	}
+
+	if opts.Size <= 0 {
+		opts.Size = readerSize(r)
+	}
+	if opts.Size <= 0 {
+		if !opts.Spool {
+			if c.uploadMethod == UploadExternal {
+				return errors.New("external upload requires the length of the content, spool contents of unknown size")
+			}
+			return c.uploadLegacy(ctx, channelID, name, r, opts)
+		}
+		f, size, err := spool(r)
+		if err != nil {
+			c.logger.Printf("[UploadReader] spooling contents failed, %s", err)
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		r, opts.Size = f, size
+	}
+
+	err := c.uploadExternal(ctx, channelID, name, r, opts)
+	if err != nil && c.uploadMethod == UploadAuto && isUnavailableMethod(err) {
+		c.logger.Printf("[UploadReader] external upload is not available, fall back to files.upload, %s", err)
+		return c.uploadLegacy(ctx, channelID, name, r, opts)
+	}
+	return err
+}
+
+// uploadLegacy uploads contents of r with files.upload.
+// The multipart body is streamed while r is read.
+// See https://api.slack.com/methods/files.upload
+func (c *Client) uploadLegacy(ctx context.Context, channelID, name string, r io.Reader, opts UploadOptions) error {
+	fields := map[string]string{
+		"channels": channelID,
+		"token":    c.token,
+	}
+	if opts.Title != "" {
+		fields["title"] = opts.Title
+	}
+	if opts.InitialComment != "" {
+		fields["initial_comment"] = opts.InitialComment
+	}
+	if opts.Filetype != "" {
+		fields["filetype"] = opts.Filetype
+	}
+	if opts.ThreadTS != "" {
+		fields["thread_ts"] = opts.ThreadTS
+	}
+	if opts.Broadcast {
+		fields["reply_broadcast"] = "true"
+	}
+
+	size := opts.Size
+	if size <= 0 {
+		size = readerSize(r)
+	}
+
+	rw := newRewinder(r)
+	var body *formBody
+	res, err := c.do(ctx, "files.upload", func() (*http.Request, error) {
+		if body != nil { // retry
+			// wait until the previous body stops reading r
+			body.Close()
+			<-body.done
+			if err := rw.rewind(); err != nil {
+				return nil, err
+			}
+		}
+
+		var err error
+		body, err = newFormBody(fields, name, newProgressReader(r, size, opts.Progress), size)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, "POST", c.buildURL("files.upload"), body)
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		req.Header.Set("Content-Type", body.contentType)
+		req.ContentLength = body.contentLength
+		return req, nil
+	})
+	if err != nil {
+		c.logger.Printf("[uploadLegacy] posting file failed, %s", err)
+		return err
+	}
+	defer res.Body.Close()
+
+	// parse and check response
+	parsed := &apiResponse{}
+	if err := json.NewDecoder(res.Body).Decode(parsed); err != nil {
+		c.logger.Printf("[uploadLegacy] decoding json response failed, %s", err)
+		return err
+	}
+	if err := parsed.err("files.upload", res); err != nil {
+		c.logger.Printf("[uploadLegacy] file upload request rejected by Slack, %s", err)
+		return err
+	}
+	return nil
+}
+
+// formBody streams a multipart form through a pipe while its file part is read
+type formBody struct {
+	*io.PipeReader
+	contentType   string
+	contentLength int64         // -1 when unknown
+	done          chan struct{} // closed when writing the form finished
+}
+
+// newFormBody returns a body made of fields and contents of r.
+// The content length is computed when size of r is known, otherwise the body is sent in chunks.
+func newFormBody(fields map[string]string, name string, r io.Reader, size int64) (*formBody, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	b := &formBody{
+		PipeReader:    pr,
+		contentType:   mw.FormDataContentType(),
+		contentLength: -1,
+		done:          make(chan struct{}),
+	}
+
+	if size > 0 {
+		// measure everything but the file content by writing the form with the same boundary
+		counter := &countWriter{}
+		cw := multipart.NewWriter(counter)
+		if err := cw.SetBoundary(mw.Boundary()); err != nil {
+			return nil, err
+		}
+		if err := writeForm(cw, fields, name, nil); err != nil {
+			return nil, err
+		}
+		b.contentLength = counter.n + size
+	}
+
+	go func() {
+		defer close(b.done)
+		pw.CloseWithError(writeForm(mw, fields, name, r))
+	}()
+	return b, nil
+}
+
+// writeForm writes fields and a file part with contents of r, then closes the form.
+func writeForm(mw *multipart.Writer, fields map[string]string, name string, r io.Reader) error {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := mw.WriteField(k, fields[k]); err != nil {
+			return err
+		}
+	}
+
+	fw, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		return err
+	}
+	if r != nil {
+		if _, err := io.Copy(fw, r); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// readerSize returns the number of bytes left in r, or zero when it cannot be known.
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }: // bytes.Reader, bytes.Buffer, strings.Reader
+		return int64(v.Len())
+	case *os.File:
+		info, err := v.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0
+		}
+		pos, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0
+		}
+		return info.Size() - pos
+	}
+	return 0
+}
+
+// countWriter counts bytes written to it
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// rewinder moves a reader back to where it was, so that a request body made from it can be sent again
+type rewinder struct {
+	seeker io.Seeker
+	start  int64
+}
+
+func newRewinder(r io.Reader) *rewinder {
+	rw := &rewinder{}
+	if seeker, ok := r.(io.Seeker); ok {
+		if start, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			rw.seeker, rw.start = seeker, start
+		}
+	}
+	return rw
+}
+
+func (rw *rewinder) rewind() error {
+	if rw.seeker == nil {
+		return errors.New("upload cannot be retried since the content is not seekable")
+	}
+	_, err := rw.seeker.Seek(rw.start, io.SeekStart)
+	return err
+}
+
+// spool copies r into a temporary file and returns the file rewound to its beginning, with its size.
+// The caller should close and remove the file.
+func spool(r io.Reader) (*os.File, int64, error) {
+	f, err := ioutil.TempFile("", "slack-upload-")
+	if err != nil {
+		return nil, 0, err
+	}
+	size, err := io.Copy(f, r)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, 0, err
+	}
+	return f, size, nil
+}
+
+// progressReader reports bytes read from r to a callback
+type progressReader struct {
+	r        io.Reader
+	total    int64
+	sent     int64
+	start    time.Time
+	callback func(UploadProgress)
+}
+
+// newProgressReader returns r as is when callback is nil
+func newProgressReader(r io.Reader, total int64, callback func(UploadProgress)) io.Reader {
+	if callback == nil {
+		return r
+	}
+	if total <= 0 {
+		total = -1
+	}
+	return &progressReader{r: r, total: total, start: time.Now(), callback: callback}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		var throughput float64
+		if elapsed := time.Since(p.start).Seconds(); elapsed > 0 {
+			throughput = float64(p.sent) / elapsed
+		}
+		p.callback(UploadProgress{Sent: p.sent, Total: p.total, Throughput: throughput})
+	}
+	return n, err
+}
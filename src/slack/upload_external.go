@@ -0,0 +1,139 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// uploadExternal uploads contents of r in three steps, obtaining an upload url, sending the contents to it,
+// and completing the upload to share the file in a channel.
+// opts.Size must hold the length of the contents.
+// See https://api.slack.com/methods/files.getUploadURLExternal and https://api.slack.com/methods/files.completeUploadExternal
+func (c *Client) uploadExternal(ctx context.Context, channelID, name string, r io.Reader, opts UploadOptions) error {
+	uploadURL, fileID, err := c.getUploadURLExternal(ctx, name, opts.Size)
+	if err != nil {
+		c.logger.Printf("[uploadExternal] obtaining upload url failed, %s", err)
+		return err
+	}
+	if err := c.sendUploadContent(ctx, uploadURL, r, opts.Size, opts.Progress); err != nil {
+		c.logger.Printf("[uploadExternal] sending contents failed, %s", err)
+		return err
+	}
+	if err := c.completeUploadExternal(ctx, fileID, channelID, opts); err != nil {
+		c.logger.Printf("[uploadExternal] completing upload failed, %s", err)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) getUploadURLExternal(ctx context.Context, name string, size int64) (string, string, error) {
+	v := url.Values{}
+	v.Set("filename", name)
+	v.Set("length", strconv.FormatInt(size, 10))
+	res, err := c.post(ctx, "files.getUploadURLExternal", strings.NewReader(v.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+
+	parsed := &struct {
+		apiResponse
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(parsed); err != nil {
+		return "", "", err
+	}
+	if err := parsed.err("files.getUploadURLExternal", res); err != nil {
+		return "", "", err
+	}
+	if parsed.UploadURL == "" || parsed.FileID == "" {
+		return "", "", errors.New("upload url is not contained in the response")
+	}
+	return parsed.UploadURL, parsed.FileID, nil
+}
+
+// sendUploadContent posts raw contents of r to an upload url.
+// The url is signed by Slack, so the token is not sent along.
+func (c *Client) sendUploadContent(ctx context.Context, uploadURL string, r io.Reader, size int64, progress func(UploadProgress)) error {
+	rw := newRewinder(r)
+	attempts := 0
+	label := uploadURL
+	if u, err := url.Parse(uploadURL); err == nil {
+		// the path is signed, so only the host is logged
+		label = u.Host
+	}
+	res, err := c.do(ctx, label, func() (*http.Request, error) {
+		if attempts > 0 {
+			if err := rw.rewind(); err != nil {
+				return nil, err
+			}
+		}
+		attempts++
+
+		body := newProgressReader(io.LimitReader(r, size), size, progress)
+		req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, ioutil.NopCloser(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.ContentLength = size
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (c *Client) completeUploadExternal(ctx context.Context, fileID, channelID string, opts UploadOptions) error {
+	type file struct {
+		ID    string `json:"id"`
+		Title string `json:"title,omitempty"`
+	}
+	files, err := json.Marshal([]file{file{ID: fileID, Title: opts.Title}})
+	if err != nil {
+		return err
+	}
+
+	v := url.Values{}
+	v.Set("files", string(files))
+	v.Set("channel_id", channelID)
+	if opts.InitialComment != "" {
+		v.Set("initial_comment", opts.InitialComment)
+	}
+	if opts.ThreadTS != "" {
+		v.Set("thread_ts", opts.ThreadTS)
+	}
+	if opts.Broadcast {
+		v.Set("reply_broadcast", "true")
+	}
+	res, err := c.post(ctx, "files.completeUploadExternal", strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	parsed := &apiResponse{}
+	if err := json.NewDecoder(res.Body).Decode(parsed); err != nil {
+		return err
+	}
+	return parsed.err("files.completeUploadExternal", res)
+}
+
+// isUnavailableMethod reports whether err means the api method does not exist on the server
+func isUnavailableMethod(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == "unknown_method" || (apiErr.Code == "" && apiErr.StatusCode == http.StatusNotFound)
+}
@@ -0,0 +1,246 @@
+package slack_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+// uploadRecorder is a files.upload stand-in which records what it received
+type uploadRecorder struct {
+	failures      int32 // number of requests to answer 429
+	count         int32
+	content       string
+	name          string
+	title         string
+	contentLength int64
+	chunked       bool
+}
+
+func (u *uploadRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if atomic.AddInt32(&u.count, 1) <= u.failures {
+		ioutil.ReadAll(r.Body)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	u.contentLength = r.ContentLength
+	u.chunked = len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked"
+	u.title = r.FormValue("title")
+	f, header, err := r.FormFile("file")
+	if err != nil {
+		json.NewEncoder(w).Encode(&struct {
+			Ok    bool   `json:"ok"`
+			Error string `json:"error"`
+		}{Ok: false, Error: "no_file_data"})
+		return
+	}
+	defer f.Close()
+	data, _ := ioutil.ReadAll(f)
+	u.content = string(data)
+	u.name = header.Filename
+	json.NewEncoder(w).Encode(&struct {
+		Ok bool `json:"ok"`
+	}{Ok: true})
+}
+
+func TestUploadReader(t *testing.T) {
+	u := &uploadRecorder{}
+	s := httptest.NewServer(u)
+	defer s.Close()
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL), slack.UploadVia(slack.UploadLegacy))
+
+	// size is known from strings.Reader
+	content := strings.Repeat("hoge foo bar\n", 1000)
+	if err := client.UploadReader(context.Background(), targetChannel, "hoge.txt", strings.NewReader(content), slack.UploadOptions{Title: targetTitle}); err != nil {
+		t.Fatalf("uploading failed, %s", err)
+	}
+	if u.content != content || u.name != "hoge.txt" || u.title != targetTitle {
+		t.Errorf("uploaded content, name or title mismatch, got name %s and title %s", u.name, u.title)
+	}
+	if u.contentLength <= int64(len(content)) || u.chunked {
+		t.Errorf("content length should be computed, got %d (chunked %v)", u.contentLength, u.chunked)
+	}
+
+	// size is unknown, so that the body is chunked
+	r := io.MultiReader(strings.NewReader("hoge"), strings.NewReader("foo"))
+	if err := client.UploadReader(context.Background(), targetChannel, "stdin", r, slack.UploadOptions{}); err != nil {
+		t.Fatalf("uploading failed, %s", err)
+	}
+	if u.content != "hogefoo" {
+		t.Errorf("expected hogefoo, got %s", u.content)
+	}
+	if !u.chunked {
+		t.Errorf("body of unknown size should be chunked, got content length %d", u.contentLength)
+	}
+}
+
+func TestUploadReaderRetry(t *testing.T) {
+	u := &uploadRecorder{failures: 1}
+	s := httptest.NewServer(u)
+	defer s.Close()
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL), slack.DisableThrottling(), slack.UploadVia(slack.UploadLegacy))
+
+	// a file can be rewound and sent again
+	f, _ := os.Open(filepath)
+	defer f.Close()
+	expected, _ := ioutil.ReadFile(filepath)
+	if err := client.UploadReader(context.Background(), targetChannel, "client.go", f, slack.UploadOptions{}); err != nil {
+		t.Fatalf("uploading failed, %s", err)
+	}
+	if u.content != string(expected) {
+		t.Error("uploaded content mismatch after retry")
+	}
+
+	// a stream cannot
+	atomic.StoreInt32(&u.count, 0)
+	r := io.MultiReader(strings.NewReader("hoge"))
+	if err := client.UploadReader(context.Background(), targetChannel, "stdin", r, slack.UploadOptions{}); err == nil {
+		t.Error("no error raised on retrying an unseekable reader")
+	}
+}
+
+// externalUploadServer is a stand-in for the external upload flow
+type externalUploadServer struct {
+	*httptest.Server
+	length    string
+	content   string
+	completed url.Values
+}
+
+func newExternalUploadServer() *externalUploadServer {
+	s := &externalUploadServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files.getUploadURLExternal", authenticate(func(w http.ResponseWriter, r *http.Request) {
+		s.length = r.FormValue("length")
+		json.NewEncoder(w).Encode(&struct {
+			Ok        bool   `json:"ok"`
+			UploadURL string `json:"upload_url"`
+			FileID    string `json:"file_id"`
+		}{Ok: true, UploadURL: s.URL + "/upload/F1", FileID: "F1"})
+	}))
+	mux.HandleFunc("/upload/F1", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		s.content = string(data)
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("/files.completeUploadExternal", authenticate(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		s.completed = r.PostForm
+		json.NewEncoder(w).Encode(&struct {
+			Ok bool `json:"ok"`
+		}{Ok: true})
+	}))
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func TestUploadExternal(t *testing.T) {
+	s := newExternalUploadServer()
+	defer s.Close()
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL))
+
+	// contents of unknown size are spooled to learn the length when asked
+	r := io.MultiReader(strings.NewReader("hoge"), strings.NewReader("foo"))
+	opts := slack.UploadOptions{Title: targetTitle, InitialComment: targetInitialComment, ThreadTS: "1500000000.000100", Broadcast: true, Spool: true}
+	if err := client.UploadReader(context.Background(), targetChannel, "hoge.txt", r, opts); err != nil {
+		t.Fatalf("uploading failed, %s", err)
+	}
+	if s.length != "7" || s.content != "hogefoo" {
+		t.Errorf("expected length 7 and content hogefoo, got %s and %s", s.length, s.content)
+	}
+	expected := url.Values{
+		"files":           []string{`[{"id":"F1","title":"` + targetTitle + `"}]`},
+		"channel_id":      []string{targetChannel},
+		"initial_comment": []string{targetInitialComment},
+		"thread_ts":       []string{"1500000000.000100"},
+		"reply_broadcast": []string{"true"},
+	}
+	if !reflect.DeepEqual(s.completed, expected) {
+		t.Errorf("completion expected %v, got %v", expected, s.completed)
+	}
+
+	// server which serves files.upload only
+	teardown := setup()
+	defer teardown()
+	client, _ = slack.NewClient(validToken, nil, slack.BaseURL(server.URL), slack.UploadVia(slack.UploadExternal))
+	if err := client.UploadFile(targetChannel, filepath, map[string]string{}); err == nil {
+		t.Error("no error raised when external upload is not available")
+	}
+	r = io.MultiReader(strings.NewReader("hoge"))
+	if err := client.UploadReader(context.Background(), targetChannel, "stdin", r, slack.UploadOptions{}); err == nil {
+		t.Error("no error raised on external upload of unknown size without spooling")
+	}
+}
+
+func TestUploadStream(t *testing.T) {
+	u := &uploadRecorder{}
+	s := httptest.NewServer(u)
+	defer s.Close()
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL))
+
+	// contents of unknown size are streamed with files.upload rather than spooled
+	r := io.MultiReader(strings.NewReader("hoge"), strings.NewReader("foo"))
+	if err := client.UploadReader(context.Background(), targetChannel, "stdin", r, slack.UploadOptions{}); err != nil {
+		t.Fatalf("uploading failed, %s", err)
+	}
+	if u.content != "hogefoo" || !u.chunked {
+		t.Errorf("expected hogefoo in a chunked body, got %s (chunked %v)", u.content, u.chunked)
+	}
+}
+
+func TestUploadFallback(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	// server lacks the external flow, so that files.upload is used
+	opts := map[string]string{
+		"title":           targetTitle,
+		"initial_comment": targetInitialComment,
+		"thread_ts":       targetTS,
+		"reply_broadcast": "true",
+	}
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(server.URL))
+	if err := client.UploadFile(targetChannel, filepath, opts); err != nil {
+		t.Errorf("uploading failed, %s", err)
+	}
+}
+
+func TestUploadProgress(t *testing.T) {
+	s := newExternalUploadServer()
+	defer s.Close()
+	client, _ := slack.NewClient(validToken, nil, slack.BaseURL(s.URL))
+
+	content := strings.Repeat("hoge", 100000)
+	var reports []slack.UploadProgress
+	opts := slack.UploadOptions{
+		Progress: func(p slack.UploadProgress) { reports = append(reports, p) },
+	}
+	if err := client.UploadReader(context.Background(), targetChannel, "hoge.txt", strings.NewReader(content), opts); err != nil {
+		t.Fatalf("uploading failed, %s", err)
+	}
+	if len(reports) == 0 {
+		t.Fatal("progress is not reported")
+	}
+	last := reports[len(reports)-1]
+	if last.Sent != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Errorf("last report should be %d of %d bytes, got %+v", len(content), len(content), last)
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i].Sent < reports[i-1].Sent {
+			t.Errorf("sent bytes decreased, %+v", reports)
+			break
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package slack
 
+import (
+	"errors"
+	"time"
+)
+
 const (
 	// SlackAPIBaseURL is endpoint of Slack web api
 	SlackAPIBaseURL = "https://slack.com/api"
@@ -15,3 +20,61 @@ func BaseURL(baseURL string) Option {
 		return nil
 	}
 }
+
+// PageLimit returns an option which sets the number of items requested per page by list methods.
+// Slack recommends a value no larger than 200.
+func PageLimit(limit int) Option {
+	return func(c *Client) error {
+		if limit < 1 {
+			return errors.New("page limit should be positive")
+		}
+		c.pageLimit = limit
+		return nil
+	}
+}
+
+// MaxRetries returns an option which sets how many times a request is retried
+// on rate limiting, 5xx status and transient network errors.
+// Zero disables retry.
+func MaxRetries(n int) Option {
+	return func(c *Client) error {
+		if n < 0 {
+			return errors.New("max retries should not be negative")
+		}
+		c.maxRetries = n
+		return nil
+	}
+}
+
+// RetryBackoff returns an option which sets the initial and the maximum wait duration of exponential backoff
+func RetryBackoff(base, max time.Duration) Option {
+	return func(c *Client) error {
+		if base <= 0 || max < base {
+			return errors.New("invalid backoff durations")
+		}
+		c.backoffBase = base
+		c.backoffMax = max
+		return nil
+	}
+}
+
+// DisableThrottling returns an option which stops a Client from pacing requests by per method tier budgets.
+// 429 responses are still retried.
+func DisableThrottling() Option {
+	return func(c *Client) error {
+		c.limiter = nil
+		return nil
+	}
+}
+
+// UploadVia returns an option which selects the api used to upload files.
+// UploadAuto is used by default.
+func UploadVia(m UploadMethod) Option {
+	return func(c *Client) error {
+		if m < UploadAuto || m > UploadLegacy {
+			return errors.New("unknown upload method")
+		}
+		c.uploadMethod = m
+		return nil
+	}
+}
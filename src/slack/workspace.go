@@ -1,9 +1,23 @@
 package slack
 
+import "strings"
+
 // Workspace holds basic information of a slack team
 type Workspace struct {
-	ID     string `json:"id"`
-	Name   string `json:"name"`
-	Domain string `json:"domain"`
-	Token  string `json:"-"`
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Domain string   `json:"domain"`
+	Token  string   `json:"-"`
+	Scopes []string `json:"-"` // scopes granted to the token
+}
+
+// parseScopes reads X-OAuth-Scopes header, which is a comma separated list of scopes
+func parseScopes(v string) []string {
+	var scopes []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+// tailChannel prints messages posted to a channel as they arrive until ctx is done.
+// Events are received over Socket Mode with appToken, or SLACK_APP_TOKEN if empty,
+// while the channel and user names are looked up with the workspace token.
+func tailChannel(ctx context.Context, channelIDOrName, appToken string) error {
+	if appToken == "" {
+		appToken = os.Getenv("SLACK_APP_TOKEN")
+	}
+	if appToken == "" {
+		return errors.New("an app-level token is required, pass --app-token or set SLACK_APP_TOKEN")
+	}
+	_, token, err := getCurrentWorkspace()
+	if err != nil {
+		logger.Printf("[tailChannel] retrieving toke from config file failed, %s", err)
+		return err
+	}
+	c, err := slack.NewClient(token, logger)
+	if err != nil {
+		logger.Printf("[tailChannel] building client failed, %s", err)
+		return err
+	}
+	channelName, channelID, members, err := toChannelAndMembers(ctx, channelIDOrName, c)
+	if err != nil {
+		logger.Printf("[tailChannel] %s", err)
+		return err
+	}
+
+	sm, err := slack.NewSocketModeClient(appToken, logger)
+	if err != nil {
+		logger.Printf("[tailChannel] building socket mode client failed, %s", err)
+		return err
+	}
+	sm.HandleConnect(func() {
+		fmt.Fprintf(os.Stderr, "Watching %s, press Ctrl+C to stop\n", channelName)
+	})
+	sm.HandleMessage(func(m *slack.MessageEvent) {
+		if m.Channel != channelID {
+			return
+		}
+		if line := formatMessageEvent(m, members); line != "" {
+			fmt.Println(line)
+		}
+	})
+
+	if err := sm.Run(ctx); err != nil && err != context.Canceled {
+		return err
+	}
+	return nil
+}
+
+// formatMessageEvent renders a message event as a line such as "15:04:05 matthewlujp: hello".
+// Replies in threads are indented, and hidden events other than edits and deletions are dropped.
+func formatMessageEvent(m *slack.MessageEvent, members slack.Members) string {
+	switch m.Subtype {
+	case "message_changed":
+		if m.Message == nil {
+			return ""
+		}
+		edited := *m.Message
+		edited.Text += " (edited)"
+		return formatMessageEvent(&edited, members)
+	case "message_deleted":
+		return fmt.Sprintf("%s (message deleted)", formatTS(m.DeletedTS))
+	}
+	if m.Hidden {
+		return ""
+	}
+
+	line := fmt.Sprintf("%s %s: %s", formatTS(m.TS), authorName(m.User, m.Username, members), m.Text)
+	if m.ThreadTS != "" && m.ThreadTS != m.TS {
+		line = "  ↳ " + line
+	}
+	return line
+}
+
+// authorName returns the user name of userID, or username of bots, falling back to the id.
+func authorName(userID, username string, members slack.Members) string {
+	if userID == "" {
+		if username != "" {
+			return username
+		}
+		return "bot"
+	}
+	if name, err := members.ID2UserName(userID); err == nil {
+		return name
+	}
+	return userID
+}
+
+// formatTS renders a message timestamp such as "1500000000.000100" in local time.
+func formatTS(ts string) string {
+	t, err := slack.ParseTS(ts)
+	if err != nil {
+		return ts
+	}
+	return t.Format("15:04:05")
+}
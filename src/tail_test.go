@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matthewlujp/slack-cmd-client/src/slack"
+)
+
+func TestFormatMessageEvent(t *testing.T) {
+	members := slack.Members{{ID: "U1", Name: "taro"}}
+	ts := "1500000000.000100"
+	clock := time.Unix(1500000000, 0).Format("15:04:05")
+
+	cases := []struct {
+		event    slack.MessageEvent
+		expected string
+	}{
+		{slack.MessageEvent{User: "U1", Text: "hoge", TS: ts}, clock + " taro: hoge"},
+		{slack.MessageEvent{User: "U2", Text: "hoge", TS: ts}, clock + " U2: hoge"},
+		{slack.MessageEvent{BotID: "B1", Username: "ci", Text: "hoge", TS: ts}, clock + " ci: hoge"},
+		{slack.MessageEvent{User: "U1", Text: "hoge", TS: ts, ThreadTS: "1400000000.000100"}, "  ↳ " + clock + " taro: hoge"},
+		{slack.MessageEvent{User: "U1", Text: "hoge", TS: ts, ThreadTS: ts}, clock + " taro: hoge"},
+		{slack.MessageEvent{Subtype: "message_changed", Hidden: true, Message: &slack.MessageEvent{User: "U1", Text: "foo", TS: ts}}, clock + " taro: foo (edited)"},
+		{slack.MessageEvent{Subtype: "message_deleted", Hidden: true, DeletedTS: ts}, clock + " (message deleted)"},
+		{slack.MessageEvent{Subtype: "message_replied", Hidden: true}, ""},
+	}
+	for _, c := range cases {
+		if line := formatMessageEvent(&c.event, members); line != c.expected {
+			t.Errorf("expected %q, got %q", c.expected, line)
+		}
+	}
+}
+
+func TestAppTokenDefault(t *testing.T) {
+	// usage prints defaults, so that the token in the environment variable should not be one
+	if def := tailCmd.Lookup("app-token").DefValue; def != "" {
+		t.Errorf("expected no default of --app-token, got %q", def)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Tokens in the config file are encrypted with AES-GCM when SLACK_CLI_PASSPHRASE is set.
+// The key is derived from the passphrase with scrypt and a random salt saved in the file,
+// and each encrypted token is saved as "encrypted:" followed by base64 of a nonce and a sealed token.
+const (
+	passphraseEnv   = "SLACK_CLI_PASSPHRASE"
+	encryptedPrefix = "encrypted:"
+
+	// scrypt parameters recommended for interactive logins
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLen      = 16
+)
+
+var (
+	errLocked        = errors.New("tokens in the config file are encrypted, set " + passphraseEnv + " to unlock them")
+	errWrongPassword = errors.New("failed to decrypt tokens, " + passphraseEnv + " may be wrong")
+)
+
+// tokenCipher encrypts and decrypts tokens with a key derived from a passphrase.
+type tokenCipher struct {
+	aead cipher.AEAD
+}
+
+func newTokenCipher(passphrase string, salt []byte) (*tokenCipher, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenCipher{aead: aead}, nil
+}
+
+func (c *tokenCipher) encrypt(token string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(token), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *tokenCipher) decrypt(value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted token %q", value)
+	}
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	token, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", errWrongPassword
+	}
+	return string(token), nil
+}
+
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// tokenFields returns pointers to all tokens in conf, including the current workspace token of the legacy format.
+func tokenFields(conf *config) []*string {
+	fields := []*string{&conf.legacyCurrentToken}
+	for i := range conf.Workspaces {
+		for j := range conf.Workspaces[i].Tokens {
+			fields = append(fields, &conf.Workspaces[i].Tokens[j].Token)
+		}
+	}
+	return fields
+}
+
+// decryptConfig decrypts tokens in conf loaded from the file in place.
+// It fails when tokens are encrypted but the passphrase is not given.
+func decryptConfig(conf *config) error {
+	var c *tokenCipher
+	for _, token := range tokenFields(conf) {
+		if !isEncrypted(*token) {
+			continue
+		}
+		if c == nil {
+			passphrase := os.Getenv(passphraseEnv)
+			if passphrase == "" {
+				return errLocked
+			}
+			salt, err := base64.StdEncoding.DecodeString(conf.EncryptionSalt)
+			if err != nil || len(salt) == 0 {
+				return fmt.Errorf("invalid encryption salt %q", conf.EncryptionSalt)
+			}
+			if c, err = newTokenCipher(passphrase, salt); err != nil {
+				return err
+			}
+		}
+		plain, err := c.decrypt(*token)
+		if err != nil {
+			return err
+		}
+		*token = plain
+	}
+	return nil
+}
+
+// encryptConfig returns a copy of conf to be saved, whose tokens are encrypted if the passphrase is given.
+// conf itself is left in plain text so that callers keep using it.
+func encryptConfig(conf *config) (*config, error) {
+	passphrase := os.Getenv(passphraseEnv)
+	if passphrase == "" {
+		return conf, nil
+	}
+
+	encrypted := conf.clone()
+	if encrypted.EncryptionSalt == "" {
+		salt := make([]byte, saltLen)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, err
+		}
+		encrypted.EncryptionSalt = base64.StdEncoding.EncodeToString(salt)
+	}
+	salt, err := base64.StdEncoding.DecodeString(encrypted.EncryptionSalt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption salt %q", encrypted.EncryptionSalt)
+	}
+	c, err := newTokenCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokenFields(encrypted) {
+		if *token == "" || isEncrypted(*token) {
+			continue
+		}
+		if *token, err = c.encrypt(*token); err != nil {
+			return nil, err
+		}
+	}
+	return encrypted, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncryptConfig(t *testing.T) {
+	defer os.Unsetenv(passphraseEnv)
+
+	conf := &config{
+		CurrentWorkspace: "000a",
+		Workspaces: []workspaceConfig{
+			{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-a"}}},
+			{ID: "000b", Name: "workspace B", Domain: "hoge-foo", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-b"}}},
+		},
+	}
+	original := conf.clone()
+
+	// tokens are kept in plain text without passphrase
+	if encrypted, err := encryptConfig(conf); err != nil || encrypted != conf {
+		t.Errorf("expected config as it is, got %v, %v", encrypted, err)
+	}
+
+	os.Setenv(passphraseEnv, "open sesame")
+	encrypted, err := encryptConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, original) {
+		t.Errorf("config should not be modified, got %v", *conf)
+	}
+	for _, token := range tokenFields(encrypted)[1:] { // legacy current token is empty
+		if !isEncrypted(*token) || strings.Contains(*token, "xoxo") {
+			t.Errorf("token is not encrypted, %s", *token)
+		}
+	}
+	if encrypted.EncryptionSalt == "" {
+		t.Error("salt is not saved")
+	}
+
+	decrypted := encrypted.clone()
+	if err := decryptConfig(decrypted); err != nil {
+		t.Fatal(err)
+	}
+	decrypted.EncryptionSalt = ""
+	if !reflect.DeepEqual(decrypted, original) {
+		t.Errorf("expected %v, got %v", original, decrypted)
+	}
+
+	locked := encrypted.clone()
+	os.Setenv(passphraseEnv, "wrong")
+	if err := decryptConfig(locked); !errors.Is(err, errWrongPassword) {
+		t.Errorf("expected errWrongPassword, got %v", err)
+	}
+	os.Unsetenv(passphraseEnv)
+	if err := decryptConfig(locked); !errors.Is(err, errLocked) {
+		t.Errorf("expected errLocked, got %v", err)
+	}
+}
+
+func TestSaveEncryptedConfig(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	defer os.Unsetenv(passphraseEnv)
+
+	os.Setenv(passphraseEnv, "open sesame")
+	saveTestWorkspaces(t)
+
+	configFilePath, err := getConfigFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "xoxo-hoge") {
+		t.Errorf("tokens are saved in plain text\n%s", data)
+	}
+
+	if _, token, err := getCurrentWorkspace(); err != nil || token != "xoxo-hoge-a" {
+		t.Errorf("expected xoxo-hoge-a, got %q, %v", token, err)
+	}
+	os.Unsetenv(passphraseEnv)
+	if _, _, err := getCurrentWorkspace(); !errors.Is(err, errLocked) {
+		t.Errorf("expected errLocked, got %v", err)
+	}
+}
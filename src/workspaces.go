@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// workspaceRecord is a registered workspace rendered by workspaces list and current
+type workspaceRecord struct {
+	ID      string   `json:"id" yaml:"id"`
+	Name    string   `json:"name" yaml:"name"`
+	Domain  string   `json:"domain" yaml:"domain"`
+	Tokens  []string `json:"tokens" yaml:"tokens"` // kinds of tokens
+	Aliases []string `json:"aliases" yaml:"aliases"`
+	Current bool     `json:"current" yaml:"current"`
+}
+
+func newWorkspaceRecord(conf *config, w workspaceConfig) workspaceRecord {
+	return workspaceRecord{
+		ID:      w.ID,
+		Name:    w.Name,
+		Domain:  w.Domain,
+		Tokens:  w.kinds(),
+		Aliases: workspaceAliases(conf, w.ID),
+		Current: w.ID == conf.CurrentWorkspace,
+	}
+}
+
+// textWorkspace renders a workspace as a line such as "* Workspace A (domain-a) tokens: bot, user aliases: a", where "*" marks the current one.
+func textWorkspace(r workspaceRecord) string {
+	line := fmt.Sprintf("  %s (%s) tokens: %s", r.Name, r.Domain, strings.Join(r.Tokens, ", "))
+	if r.Current {
+		line = "*" + line[1:]
+	}
+	if len(r.Aliases) > 0 {
+		line += " aliases: " + strings.Join(r.Aliases, ", ")
+	}
+	return line
+}
+
+// listRegisteredWorkspaces writes registered workspaces, marking the current one.
+func listRegisteredWorkspaces(w io.Writer) error {
+	conf := &config{}
+	if err := loadConfig(conf); err != nil {
+		logger.Printf("[listRegisteredWorkspaces] loading config failed, %s", err)
+		return err
+	}
+
+	records := make([]workspaceRecord, 0, len(conf.Workspaces))
+	for _, ws := range conf.Workspaces {
+		records = append(records, newWorkspaceRecord(conf, ws))
+	}
+	if *outputFormat != outputText {
+		return renderOutput(w, *outputFormat, *outputTmpl, records)
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(w, "No workspace is registered.")
+		return nil
+	}
+	for _, r := range records {
+		if _, err := fmt.Fprintln(w, textWorkspace(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printCurrentWorkspace writes the workspace which commands use, which is the one given with --workspace or SLACK_WORKSPACE if any.
+func printCurrentWorkspace(w io.Writer) error {
+	if token, err := envToken(); err != nil {
+		return err
+	} else if token != "" {
+		return errUnknownWorkspace
+	}
+	conf := &config{}
+	if err := loadConfig(conf); err != nil {
+		logger.Printf("[printCurrentWorkspace] loading config failed, %s", err)
+		return err
+	}
+
+	current, err := currentWorkspace(conf)
+	if err != nil {
+		return err
+	}
+
+	r := newWorkspaceRecord(conf, *current)
+	if *outputFormat != outputText {
+		return renderOutput(w, *outputFormat, *outputTmpl, []workspaceRecord{r})
+	}
+	_, err = fmt.Fprintln(w, r.Name)
+	return err
+}
+
+// unregisterWorkspace removes a workspace and its token from the config file.
+func unregisterWorkspace(key string) error {
+	var removed workspaceConfig
+	var noCurrent bool
+	if err := updateConfig(func(conf *config) error {
+		var err error
+		if removed, err = removeWorkspace(conf, key); err != nil {
+			return err
+		}
+		noCurrent = conf.CurrentWorkspace == "" && len(conf.Workspaces) > 0
+		return nil
+	}); err != nil {
+		logger.Printf("[unregisterWorkspace] removing workspace failed, %s", err)
+		return err
+	}
+
+	fmt.Printf("Workspace %s removed.\n", removed.Name)
+	if noCurrent {
+		fmt.Println("No workspace is current now. Choose one with switch.")
+	}
+	return nil
+}
+
+// aliasWorkspace gives a short alias to a workspace, which can be used in place of its name.
+func aliasWorkspace(key, alias string) error {
+	var name string
+	if err := updateConfig(func(conf *config) error {
+		ws, err := setAlias(conf, key, alias)
+		if err != nil {
+			return err
+		}
+		name = ws.Name
+		return nil
+	}); err != nil {
+		logger.Printf("[aliasWorkspace] setting alias failed, %s", err)
+		return err
+	}
+
+	fmt.Printf("%s is now an alias of workspace %s.\n", alias, name)
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func saveTestWorkspaces(t *testing.T) {
+	conf := &config{
+		CurrentWorkspace: "000a",
+		Workspaces: []workspaceConfig{
+			{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-a"}}},
+			{ID: "000b", Name: "workspace B", Domain: "hoge-foo", Tokens: []tokenConfig{{Kind: tokenUser, Token: "xoxo-hoge-b"}}},
+		},
+	}
+	if err := saveConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAliasWorkspace(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	saveTestWorkspaces(t)
+
+	if err := aliasWorkspace("workspace B", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := aliasWorkspace("000a", "b"); err == nil {
+		t.Error("no error raised on an alias used for another workspace")
+	}
+	if err := aliasWorkspace("b", "hoge-foo"); err != nil {
+		t.Errorf("domain of the same workspace should be accepted as an alias, got %v", err)
+	}
+	if err := aliasWorkspace("workspace A", "hoge-foo"); err == nil {
+		t.Error("no error raised on an alias which is a domain of another workspace")
+	}
+
+	// aliases are accepted anywhere names are
+	if err := switchWorkspace("b"); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := printCurrentWorkspace(&b); err != nil || b.String() != "workspace B\n" {
+		t.Errorf("expected workspace B to be current, got %q, %v", b.String(), err)
+	}
+
+	b.Reset()
+	if err := listRegisteredWorkspaces(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := "  workspace A (foo-bar) tokens: user\n* workspace B (hoge-foo) tokens: user aliases: b, hoge-foo\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestUnregisterWorkspace(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	saveTestWorkspaces(t)
+
+	if err := aliasWorkspace("workspace A", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := unregisterWorkspace("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &config{}
+	if err := loadConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	if len(conf.Workspaces) != 1 || conf.Workspaces[0].ID != "000b" {
+		t.Errorf("expected only workspace B to be left, got %v", conf.Workspaces)
+	}
+	if len(conf.Aliases) != 0 || conf.CurrentWorkspace != "" {
+		t.Errorf("aliases and current workspace should be cleared, got %v and %q", conf.Aliases, conf.CurrentWorkspace)
+	}
+	if err := printCurrentWorkspace(&bytes.Buffer{}); !errors.Is(err, errNoCurrentWorkspace) {
+		t.Errorf("expected errNoCurrentWorkspace, got %v", err)
+	}
+	if err := unregisterWorkspace("a"); !errors.Is(err, errWorkspaceNotRegistered) {
+		t.Errorf("expected errWorkspaceNotRegistered, got %v", err)
+	}
+}
+
+func TestRunWorkspacesCommand(t *testing.T) {
+	for _, args := range [][]string{nil, {"list", "foo"}, {"remove"}, {"alias", "a"}, {"rename", "a", "b"}} {
+		if err := runWorkspacesCommand(args); err == nil {
+			t.Errorf("%v: no error raised on invalid arguments", args)
+		}
+	}
+}
+
+func TestNewWorkspaceRecord(t *testing.T) {
+	conf := &config{
+		CurrentWorkspace: "000a",
+		Aliases:          map[string]string{"a": "000a", "b": "000b", "aa": "000a"},
+	}
+	r := newWorkspaceRecord(conf, workspaceConfig{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []tokenConfig{
+		{Kind: tokenBot, Token: "xoxb-hoge-a"},
+		{Kind: tokenUser, Token: "xoxp-hoge-a"},
+	}})
+	expected := workspaceRecord{ID: "000a", Name: "workspace A", Domain: "foo-bar", Tokens: []string{"bot", "user"}, Aliases: []string{"a", "aa"}, Current: true}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected %+v, got %+v", expected, r)
+	}
+}
+
+func TestRenderWorkspaceWithoutAliases(t *testing.T) {
+	conf := &config{CurrentWorkspace: "000a"}
+	r := newWorkspaceRecord(conf, workspaceConfig{ID: "000b", Name: "workspace B", Domain: "hoge-foo"})
+
+	var b bytes.Buffer
+	if err := renderOutput(&b, outputJSON, "", []workspaceRecord{r}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"aliases": []`) || !strings.Contains(b.String(), `"tokens": []`) {
+		t.Errorf("expected empty lists rather than null, got %s", b.String())
+	}
+}